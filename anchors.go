@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Anchor is a position in the rendered page that a #fragment or the table
+// of contents can jump to
+type Anchor struct {
+	IDs   []string
+	Level int // heading level, 0 for plain id targets
+	Text  string
+	Line  int // line in the rendered output
+}
+
+// Markers are inserted into the Markdown before rendering and stripped
+// afterwards, leaving the line each anchor ended up on
+var anchorMarkerPattern = regexp.MustCompile(`⟦(\d+)⟧`)
+
+func anchorMarker(index int) string {
+	return fmt.Sprintf("⟦%d⟧", index)
+}
+
+// anchorTargets maps each block element that will be rendered to the ids
+// that should land on it. Ids on wrappers resolve to the enclosing block or
+// the first block inside them.
+func anchorTargets(root *goquery.Selection, blocks string) map[*html.Node][]string {
+	targets := make(map[*html.Node][]string)
+	root.Find("[id], a[name]").Each(func(i int, s *goquery.Selection) {
+		id, ok := s.Attr("id")
+		if !ok || id == "" {
+			id, _ = s.Attr("name")
+		}
+		if id == "" {
+			return
+		}
+
+		target := s
+		if !s.Is(blocks) {
+			target = s.Closest(blocks)
+			if target.Length() == 0 {
+				target = s.Find(blocks).First()
+			}
+		}
+		if target.Length() == 0 {
+			return
+		}
+		node := target.Get(0)
+		targets[node] = append(targets[node], id)
+	})
+	return targets
+}
+
+// addAnchor registers s as an anchor if it is a heading or an id target and
+// returns the marker to prefix its text with
+func addAnchor(
+	anchors *[]Anchor,
+	targets map[*html.Node][]string,
+	s *goquery.Selection,
+	text string,
+) string {
+	level := 0
+	switch goquery.NodeName(s) {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ = strconv.Atoi(goquery.NodeName(s)[1:])
+	}
+	ids := targets[s.Get(0)]
+	if level == 0 && len(ids) == 0 {
+		return ""
+	}
+	*anchors = append(*anchors, Anchor{IDs: ids, Level: level, Text: text})
+	return anchorMarker(len(*anchors) - 1)
+}
+
+// renderWithAnchors renders Markdown containing anchor markers, records the
+// output line of each anchor and strips the markers
+func renderWithAnchors(content string, anchors []Anchor) (string, []Anchor, error) {
+	styled, err := renderWithStyle(content)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(anchors) == 0 {
+		return styled, anchors, nil
+	}
+
+	lines := strings.Split(styled, "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "⟦") {
+			continue
+		}
+		for _, match := range anchorMarkerPattern.FindAllStringSubmatch(line, -1) {
+			if index, err := strconv.Atoi(match[1]); err == nil && index < len(anchors) {
+				anchors[index].Line = i
+			}
+		}
+		lines[i] = anchorMarkerPattern.ReplaceAllString(line, "")
+	}
+	return strings.Join(lines, "\n"), anchors, nil
+}
+
+// stripAnchorMarkers removes the markers from unrendered Markdown
+func stripAnchorMarkers(content string) string {
+	return anchorMarkerPattern.ReplaceAllString(content, "")
+}
+
+// findAnchor returns the rendered line for a fragment id
+func findAnchor(anchors []Anchor, fragment string) (int, bool) {
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	for _, anchor := range anchors {
+		for _, id := range anchor.IDs {
+			if id == fragment {
+				return anchor.Line, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// sameDocument reports whether target only differs from current by its fragment
+func sameDocument(current, target string) (string, bool) {
+	base, fragment, found := strings.Cut(target, "#")
+	if !found {
+		return "", false
+	}
+	currentBase, _, _ := strings.Cut(current, "#")
+	return fragment, base == currentBase
+}
+
+// headings returns the anchors that belong in the table of contents
+func headings(anchors []Anchor) []Anchor {
+	var result []Anchor
+	for _, anchor := range anchors {
+		if anchor.Level > 0 {
+			result = append(result, anchor)
+		}
+	}
+	return result
+}
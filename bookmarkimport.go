@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// normalizeURL reduces a URL to a form used to spot duplicate bookmarks:
+// lower-case scheme and host, no default port, fragment or trailing slash
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(raw)), "/")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+	u.Host = strings.TrimPrefix(u.Host, "www.")
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// parseBookmarkFile reads bookmarks from a Netscape HTML export, a Chromium
+// Bookmarks file, a Firefox JSON backup or a plain list of URLs
+func parseBookmarkFile(data []byte) ([]Bookmark, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		return parseBookmarkJSON(trimmed)
+	case bytes.Contains(bytes.ToUpper(trimmed), []byte("<DL")):
+		return parseNetscapeBookmarks(bytes.NewReader(data))
+	default:
+		return parseURLList(data), nil
+	}
+}
+
+// parseNetscapeBookmarks walks the <DL> tree of a Netscape bookmark file.
+// Nested <H3> folders become a "/" separated folder path.
+func parseNetscapeBookmarks(r io.Reader) ([]Bookmark, error) {
+	var bookmarks []Bookmark
+	var folders []string
+	pendingFolder := ""
+	tokenizer := html.NewTokenizer(r)
+
+	// text collects the text up to the next tag and leaves that tag for the
+	// main loop to handle
+	var tokenType html.TokenType
+	pending := false
+	text := func() string {
+		var b strings.Builder
+		for tokenType = tokenizer.Next(); tokenType == html.TextToken; tokenType = tokenizer.Next() {
+			b.Write(tokenizer.Text())
+		}
+		pending = true
+		return strings.TrimSpace(b.String())
+	}
+
+	for {
+		if !pending {
+			tokenType = tokenizer.Next()
+		}
+		pending = false
+
+		switch tokenType {
+		case html.ErrorToken:
+			if tokenizer.Err() == io.EOF {
+				return bookmarks, nil
+			}
+			return bookmarks, tokenizer.Err()
+
+		case html.StartTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "h3":
+				pendingFolder = text()
+				// Browsers export their toolbar as a folder; keep it top level
+				for _, attr := range token.Attr {
+					if strings.EqualFold(attr.Key, "personal_toolbar_folder") {
+						pendingFolder = ""
+					}
+				}
+			case "dl":
+				folders = append(folders, pendingFolder)
+				pendingFolder = ""
+			case "a":
+				bookmark := Bookmark{Created: time.Now()}
+				for _, attr := range token.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "href":
+						bookmark.URL = attr.Val
+					case "tags":
+						bookmark.addTags(strings.Split(attr.Val, ","))
+					case "add_date":
+						if secs, err := strconv.ParseInt(attr.Val, 10, 64); err == nil && secs > 0 {
+							bookmark.Created = time.Unix(secs, 0)
+						}
+					}
+				}
+				bookmark.Title = text()
+				bookmark.Folder = joinFolders(folders)
+				if strings.HasPrefix(bookmark.URL, "http") {
+					bookmarks = append(bookmarks, bookmark)
+				}
+			case "dd":
+				description := text()
+				if len(bookmarks) > 0 {
+					bookmarks[len(bookmarks)-1].Description = description
+				}
+			}
+
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "dl" && len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+		}
+	}
+}
+
+// joinFolders turns a stack of folder names into a folder path
+func joinFolders(folders []string) string {
+	var parts []string
+	for _, folder := range folders {
+		if folder = strings.Trim(folder, "/ "); folder != "" {
+			parts = append(parts, folder)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// bookmarkNode covers both Chromium's Bookmarks file and Firefox's JSON
+// backup, which share the nested children layout under different names
+type bookmarkNode struct {
+	// Chromium
+	Type      string                  `json:"type"`
+	Name      string                  `json:"name"`
+	URL       string                  `json:"url"`
+	DateAdded string                  `json:"date_added"`
+	Roots     map[string]bookmarkNode `json:"roots"`
+
+	// Firefox
+	Title        string `json:"title"`
+	URI          string `json:"uri"`
+	Tags         string `json:"tags"`
+	Root         string `json:"root"`
+	FirefoxAdded int64  `json:"dateAdded"`
+
+	Children []bookmarkNode `json:"children"`
+}
+
+// Chromium stores times as microseconds since 1601-01-01
+const chromiumEpochOffset = 11644473600000000
+
+func parseBookmarkJSON(data []byte) ([]Bookmark, error) {
+	var root bookmarkNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	var bookmarks []Bookmark
+	var walk func(node bookmarkNode, folders []string)
+	walk = func(node bookmarkNode, folders []string) {
+		switch {
+		case node.Type == "url":
+			bookmark := Bookmark{Title: node.Name, URL: node.URL, Folder: joinFolders(folders), Created: time.Now()}
+			if micros, err := strconv.ParseInt(node.DateAdded, 10, 64); err == nil && micros > chromiumEpochOffset {
+				bookmark.Created = time.UnixMicro(micros - chromiumEpochOffset)
+			}
+			bookmarks = append(bookmarks, bookmark)
+
+		case node.Type == "text/x-moz-place":
+			if !strings.HasPrefix(node.URI, "http") {
+				return
+			}
+			bookmark := Bookmark{Title: node.Title, URL: node.URI, Folder: joinFolders(folders), Created: time.Now()}
+			if node.Tags != "" {
+				bookmark.addTags(strings.Split(node.Tags, ","))
+			}
+			if node.FirefoxAdded > 0 {
+				bookmark.Created = time.UnixMicro(node.FirefoxAdded)
+			}
+			bookmarks = append(bookmarks, bookmark)
+
+		default:
+			// A folder; the browsers' own roots ("Bookmarks bar", "Bookmarks
+			// Menu") are left out of the path
+			name := node.Name
+			if node.Type == "text/x-moz-place-container" {
+				name = node.Title
+			}
+			if node.Root != "" {
+				name = ""
+			}
+			sub := append(folders[:len(folders):len(folders)], name)
+			for _, child := range node.Children {
+				walk(child, sub)
+			}
+		}
+	}
+
+	if len(root.Roots) > 0 {
+		// Chromium: bookmark_bar, other and synced
+		keys := make([]string, 0, len(root.Roots))
+		for key := range root.Roots {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, child := range root.Roots[key].Children {
+				walk(child, nil)
+			}
+		}
+		return bookmarks, nil
+	}
+	if root.Type == "text/x-moz-place-container" {
+		walk(root, nil)
+		return bookmarks, nil
+	}
+	return nil, fmt.Errorf("unrecognized bookmarks JSON")
+}
+
+// parseURLList reads one URL per line, ignoring blanks and # comments
+func parseURLList(data []byte) []Bookmark {
+	var bookmarks []Bookmark
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		link, title, _ := strings.Cut(line, " ")
+		if !strings.Contains(link, "://") {
+			link = "https://" + link
+		}
+		if title = strings.TrimSpace(title); title == "" {
+			title = link
+		}
+		bookmarks = append(bookmarks, Bookmark{Title: title, URL: link, Created: time.Now()})
+	}
+	return bookmarks
+}
+
+// importBookmarks merges bookmarks from filename, skipping URLs that are
+// already bookmarked. It returns how many were added and skipped.
+func (m *model) importBookmarks(filename string) (int, int, error) {
+	data, err := os.ReadFile(expandPath(filename))
+	if err != nil {
+		return 0, 0, err
+	}
+	parsed, err := parseBookmarkFile(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	added, skipped := 0, 0
+	m.updateBookmarks(func(bookmarks []Bookmark) []Bookmark {
+		seen := make(map[string]bool)
+		for _, bookmark := range bookmarks {
+			seen[normalizeURL(bookmark.URL)] = true
+		}
+		for _, bookmark := range parsed {
+			bookmark.URL = cleanLink(bookmark.URL)
+			key := normalizeURL(bookmark.URL)
+			if seen[key] {
+				skipped++
+				continue
+			}
+			seen[key] = true
+			if bookmark.Title == "" {
+				bookmark.Title = bookmark.URL
+			}
+			bookmarks = append(bookmarks, bookmark)
+			added++
+		}
+		return bookmarks
+	})
+	return added, skipped, nil
+}
+
+// exportBookmarks writes all bookmarks to filename. The extension picks the
+// format: .html for Netscape HTML, .json for Chromium, anything else for a
+// plain URL list.
+func (m *model) exportBookmarks(filename string) error {
+	var data []byte
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm":
+		data = []byte(netscapeBookmarks(m.bookmarks))
+	case ".json":
+		var err error
+		if data, err = chromiumBookmarks(m.bookmarks); err != nil {
+			return err
+		}
+	default:
+		var b strings.Builder
+		for _, bookmark := range m.bookmarks {
+			b.WriteString(bookmark.URL + " " + bookmark.Title + "\n")
+		}
+		data = []byte(b.String())
+	}
+	return writeFileAtomic(expandPath(filename), data)
+}
+
+// bookmarkFolder is a folder tree built from bookmark folder paths
+type bookmarkFolder struct {
+	name       string
+	subfolders []*bookmarkFolder
+	bookmarks  []Bookmark
+}
+
+func (f *bookmarkFolder) subfolder(name string) *bookmarkFolder {
+	for _, sub := range f.subfolders {
+		if sub.name == name {
+			return sub
+		}
+	}
+	sub := &bookmarkFolder{name: name}
+	f.subfolders = append(f.subfolders, sub)
+	return sub
+}
+
+func buildFolderTree(bookmarks []Bookmark) *bookmarkFolder {
+	root := &bookmarkFolder{}
+	for _, bookmark := range bookmarks {
+		folder := root
+		if bookmark.Folder != "" {
+			for _, name := range strings.Split(bookmark.Folder, "/") {
+				folder = folder.subfolder(name)
+			}
+		}
+		folder.bookmarks = append(folder.bookmarks, bookmark)
+	}
+	return root
+}
+
+func netscapeBookmarks(bookmarks []Bookmark) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	b.WriteString("<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n")
+	b.WriteString("<TITLE>Bookmarks</TITLE>\n<H1>Bookmarks</H1>\n")
+
+	var write func(folder *bookmarkFolder, indent string)
+	write = func(folder *bookmarkFolder, indent string) {
+		b.WriteString(indent + "<DL><p>\n")
+		for _, sub := range folder.subfolders {
+			b.WriteString(fmt.Sprintf("%s    <DT><H3>%s</H3>\n", indent, html.EscapeString(sub.name)))
+			write(sub, indent+"    ")
+		}
+		for _, bookmark := range folder.bookmarks {
+			b.WriteString(fmt.Sprintf("%s    <DT><A HREF=\"%s\" ADD_DATE=\"%d\"",
+				indent, html.EscapeString(bookmark.URL), bookmark.Created.Unix()))
+			if len(bookmark.Tags) > 0 {
+				b.WriteString(fmt.Sprintf(" TAGS=\"%s\"", html.EscapeString(strings.Join(bookmark.Tags, ","))))
+			}
+			b.WriteString(fmt.Sprintf(">%s</A>\n", html.EscapeString(bookmark.Title)))
+			if bookmark.Description != "" {
+				b.WriteString(fmt.Sprintf("%s    <DD>%s\n", indent, html.EscapeString(bookmark.Description)))
+			}
+		}
+		b.WriteString(indent + "</DL><p>\n")
+	}
+	write(buildFolderTree(bookmarks), "")
+	return b.String()
+}
+
+func chromiumBookmarks(bookmarks []Bookmark) ([]byte, error) {
+	nextID := 1
+	newID := func() string {
+		nextID++
+		return strconv.Itoa(nextID)
+	}
+	chromiumTime := func(t time.Time) string {
+		return strconv.FormatInt(t.UnixMicro()+chromiumEpochOffset, 10)
+	}
+
+	var convert func(folder *bookmarkFolder) []map[string]any
+	convert = func(folder *bookmarkFolder) []map[string]any {
+		var children []map[string]any
+		for _, sub := range folder.subfolders {
+			children = append(children, map[string]any{
+				"type":       "folder",
+				"id":         newID(),
+				"name":       sub.name,
+				"date_added": chromiumTime(time.Now()),
+				"children":   convert(sub),
+			})
+		}
+		for _, bookmark := range folder.bookmarks {
+			children = append(children, map[string]any{
+				"type":       "url",
+				"id":         newID(),
+				"name":       bookmark.Title,
+				"url":        bookmark.URL,
+				"date_added": chromiumTime(bookmark.Created),
+			})
+		}
+		if children == nil {
+			children = []map[string]any{}
+		}
+		return children
+	}
+
+	file := map[string]any{
+		"version": 1,
+		"roots": map[string]any{
+			"bookmark_bar": map[string]any{
+				"type": "folder", "id": "1", "name": "Bookmarks bar",
+				"children": convert(buildFolderTree(bookmarks)),
+			},
+			"other": map[string]any{
+				"type": "folder", "id": newID(), "name": "Other bookmarks",
+				"children": []map[string]any{},
+			},
+		},
+	}
+	return json.MarshalIndent(file, "", "   ")
+}
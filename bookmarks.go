@@ -2,33 +2,56 @@ package main
 
 import (
 	"encoding/json"
-	"log"
+	"math"
 	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 )
 
-func loadBookmarks(filename string) []Bookmark {
+// Version of the bookmarks.json layout. Version 1 was a bare JSON list of
+// {title, url} objects and is still read.
+const bookmarkFormatVersion = 2
 
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return []Bookmark{}
+// newBookmarkStore returns the store for the bookmarks file at filename
+func newBookmarkStore(filename string) *jsonStore[Bookmark] {
+	return &jsonStore[Bookmark]{
+		file:    filename,
+		key:     "bookmarks",
+		version: bookmarkFormatVersion,
+		name:    "bookmarks",
+		upgrade: func(data []byte) ([]Bookmark, error) {
+			var bookmarks []Bookmark
+			if err := json.Unmarshal(data, &bookmarks); err != nil {
+				return nil, err
+			}
+			created := time.Now()
+			if info, err := os.Stat(filename); err == nil {
+				created = info.ModTime()
+			}
+			for i := range bookmarks {
+				bookmarks[i].Created = created
+			}
+			return bookmarks, nil
+		},
 	}
-	var bookmarks []Bookmark
-	if err := json.Unmarshal(data, &bookmarks); err != nil {
-		log.Printf("Error loading bookmarks: %v", err)
-		return []Bookmark{}
-	}
-	return bookmarks
 }
 
-func (m *model) saveBookmarks() {
-	data, err := json.MarshalIndent(m.bookmarks, "", "  ")
-	if err != nil {
-		log.Printf("Error saving bookmarks: %v", err)
-		return
-	}
-	if err := os.WriteFile(m.bookmarkFile, data, 0644); err != nil {
-		log.Printf("Error writing bookmarks file: %v", err)
-	}
+// refreshBookmarks reloads the file if another instance changed it
+func (m *model) refreshBookmarks() {
+	m.bookmarkStore.refresh(&m.bookmarks)
+}
+
+// updateBookmarks applies change while holding the file lock, starting
+// from whatever another instance may have saved in the meantime
+func (m *model) updateBookmarks(change func([]Bookmark) []Bookmark) {
+	m.bookmarkStore.update(&m.bookmarks, change)
+}
+
+// bookmarkIndex finds a bookmark by URL, or -1
+func bookmarkIndex(bookmarks []Bookmark, url string) int {
+	return slices.IndexFunc(bookmarks, func(b Bookmark) bool { return b.URL == url })
 }
 
 func (m *model) isBookmarked(url string) bool {
@@ -45,14 +68,114 @@ func (m *model) addBookmark(title string) {
 	if activeTab == nil || len(activeTab.History) == 0 || activeTab.CurrentPos < 0 {
 		return
 	}
-	currentURL := activeTab.History[activeTab.CurrentPos]
-	if m.isBookmarked(currentURL) {
+	currentURL := cleanLink(activeTab.History[activeTab.CurrentPos].URL)
+	now := time.Now()
+	bookmark := Bookmark{
+		Title:       title,
+		URL:         currentURL,
+		Created:     now,
+		LastVisited: now,
+	}
+	m.updateBookmarks(func(bookmarks []Bookmark) []Bookmark {
+		if bookmarkIndex(bookmarks, currentURL) >= 0 {
+			return bookmarks
+		}
+		return append(bookmarks, bookmark)
+	})
+}
+
+// touchBookmark records a visit to url if it is bookmarked
+func (m *model) touchBookmark(url string) {
+	if !m.isBookmarked(url) {
 		return
 	}
-	bookmark := Bookmark{
-		Title: title,
-		URL:   currentURL,
+	m.updateBookmarks(func(bookmarks []Bookmark) []Bookmark {
+		if i := bookmarkIndex(bookmarks, url); i >= 0 {
+			bookmarks[i].LastVisited = time.Now()
+		}
+		return bookmarks
+	})
+}
+
+// hasTag reports whether the bookmark carries tag, ignoring case
+func (b *Bookmark) hasTag(tag string) bool {
+	for _, t := range b.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// addTags adds tags the bookmark doesn't have yet
+func (b *Bookmark) addTags(tags []string) {
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(tag, "#")
+		if tag != "" && !b.hasTag(tag) {
+			b.Tags = append(b.Tags, tag)
+		}
+	}
+}
+
+// removeTags drops the given tags
+func (b *Bookmark) removeTags(tags []string) {
+	b.Tags = slices.DeleteFunc(b.Tags, func(t string) bool {
+		for _, tag := range tags {
+			if strings.EqualFold(t, strings.TrimPrefix(tag, "#")) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// filterBookmarks returns the URLs of bookmarks matching query, grouped
+// by folder. URLs stay valid when another instance reorders the file. "#tag" terms require a tag, "@folder" terms pick a folder and
+// the remaining words are fuzzy matched against title, URL and description.
+func (m *model) filterBookmarks(query string) []string {
+	var tags, folders, words []string
+	for _, term := range strings.Fields(query) {
+		switch {
+		case strings.HasPrefix(term, "#") && len(term) > 1:
+			tags = append(tags, term[1:])
+		case strings.HasPrefix(term, "@") && len(term) > 1:
+			folders = append(folders, strings.ToLower(term[1:]))
+		default:
+			words = append(words, strings.ToLower(term))
+		}
+	}
+	text := strings.Join(words, " ")
+
+	var indices []int
+	scores := make(map[int]float64)
+	for i := range m.bookmarks {
+		bookmark := &m.bookmarks[i]
+		if slices.ContainsFunc(tags, func(tag string) bool { return !bookmark.hasTag(tag) }) {
+			continue
+		}
+		if len(folders) > 0 && !slices.Contains(folders, strings.ToLower(bookmark.Folder)) {
+			continue
+		}
+		titleScore, titleOK := fuzzyScore(text, strings.ToLower(bookmark.Title))
+		urlScore, urlOK := fuzzyScore(text, strings.ToLower(stripScheme(bookmark.URL)))
+		descScore, descOK := fuzzyScore(text, strings.ToLower(bookmark.Description))
+		if !titleOK && !urlOK && !descOK {
+			continue
+		}
+		indices = append(indices, i)
+		scores[i] = math.Max(titleScore, math.Max(urlScore, descScore))
+	}
+
+	sort.SliceStable(indices, func(a, b int) bool {
+		folderA, folderB := m.bookmarks[indices[a]].Folder, m.bookmarks[indices[b]].Folder
+		if folderA != folderB {
+			return folderA < folderB
+		}
+		return scores[indices[a]] > scores[indices[b]]
+	})
+	urls := make([]string, len(indices))
+	for i, index := range indices {
+		urls[i] = m.bookmarks[index].URL
 	}
-	m.bookmarks = append(m.bookmarks, bookmark)
-	m.saveBookmarks()
+	return urls
 }
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const maxSuggestions = 8
+
+// Only the most frecent history entries are offered, so typing stays fast
+// with a long history
+const maxHistoryCandidates = 5000
+
+// Suggestion is a single entry in the URL bar dropdown
+type Suggestion struct {
+	Title  string
+	URL    string
+	Source string // "history", "bookmark", "tab", "keyword" or "engine"
+	TabID  int    // Tab.ID of an open tab
+	score  float64
+
+	// Lowercased forms matched against the query
+	matchURL   string
+	matchTitle string
+}
+
+func newSuggestion(title, url, source string, score float64) Suggestion {
+	return Suggestion{
+		Title:      title,
+		URL:        url,
+		Source:     source,
+		score:      score,
+		matchURL:   strings.ToLower(stripScheme(url)),
+		matchTitle: strings.ToLower(title),
+	}
+}
+
+// Commands offered as completions when typed into the URL bar
+var commandKeywords = []string{"help", "history", "bookmarks", "images", "reader", "tabs", "engines", "rewrites", "filters", "copy", "private", "feeds", "subscribe", "later"}
+
+// frecency combines visit count with a recency bucket, similar to Firefox
+func frecency(count int, lastVisit time.Time) float64 {
+	if count == 0 {
+		return 0
+	}
+	age := time.Since(lastVisit)
+	var weight float64
+	switch {
+	case age < 4*24*time.Hour:
+		weight = 100
+	case age < 14*24*time.Hour:
+		weight = 70
+	case age < 31*24*time.Hour:
+		weight = 50
+	case age < 90*24*time.Hour:
+		weight = 30
+	default:
+		weight = 10
+	}
+	return float64(count) * weight
+}
+
+// fuzzyScore matches pattern as a case-insensitive subsequence of text.
+// Consecutive runs, word starts and substring hits score higher.
+func fuzzyScore(pattern, text string) (float64, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	if idx := strings.Index(text, pattern); idx >= 0 {
+		score := 100 + float64(len(pattern))*10
+		if idx == 0 {
+			score += 50
+		} else if !isWordRune(rune(text[idx-1])) {
+			score += 25
+		}
+		return score, true
+	}
+
+	var score float64
+	pi := 0
+	patternRunes := []rune(pattern)
+	prevMatched := false
+	prev := ' '
+	for _, r := range text {
+		if pi < len(patternRunes) && r == patternRunes[pi] {
+			score += 1
+			if prevMatched {
+				score += 5
+			}
+			if !isWordRune(prev) {
+				score += 3
+			}
+			pi++
+			prevMatched = true
+		} else {
+			prevMatched = false
+		}
+		prev = r
+	}
+	if pi < len(patternRunes) {
+		return 0, false
+	}
+	return score, true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// stripScheme drops the protocol and leading www. for matching and display
+func stripScheme(url string) string {
+	url = strings.TrimPrefix(url, "https://")
+	url = strings.TrimPrefix(url, "http://")
+	return strings.TrimPrefix(url, "www.")
+}
+
+// completionCandidates gathers every possible suggestion before matching
+func (m *model) completionCandidates() []Suggestion {
+	var candidates []Suggestion
+	seen := make(map[string]int)
+
+	add := func(s Suggestion) {
+		if i, ok := seen[s.URL]; ok {
+			// Keep the strongest source for duplicate URLs
+			if candidates[i].Title == "" {
+				candidates[i].Title = s.Title
+			}
+			candidates[i].score += s.score
+			if s.Source == "tab" {
+				candidates[i].Source = "tab"
+				candidates[i].TabID = s.TabID
+			}
+			return
+		}
+		seen[s.URL] = len(candidates)
+		candidates = append(candidates, s)
+	}
+
+	for _, candidate := range m.completionHistory() {
+		add(candidate)
+	}
+	for _, bookmark := range m.bookmarks {
+		add(newSuggestion(bookmark.Title, bookmark.URL, "bookmark", 140))
+	}
+	for i, tab := range m.tabs {
+		if i == m.activeTab || tab.URL == "" || strings.HasPrefix(tab.URL, "help://") {
+			continue
+		}
+		suggestion := newSuggestion(tab.Title, tab.URL, "tab", 50)
+		suggestion.TabID = tab.ID
+		add(suggestion)
+	}
+	for _, keyword := range commandKeywords {
+		add(newSuggestion(keyword, keyword, "keyword", 0))
+	}
+	// Engine keywords complete to "<keyword> " ready for the query
+	for _, engine := range m.searchEngines() {
+		if engine.Keyword != "" {
+			add(newSuggestion(engine.Name, engine.Keyword+" ", "engine", 0))
+		}
+	}
+
+	return candidates
+}
+
+// completionHistory returns the history part of the candidates, one per
+// URL as the store keeps them, capped at the most frecent entries. It is only rebuilt when
+// the history changes, not on every keystroke.
+func (m *model) completionHistory() []Suggestion {
+	if m.historyCandidates != nil && m.historyCandidatesVersion == m.history.version {
+		return m.historyCandidates
+	}
+	candidates := make([]Suggestion, 0, len(m.history.entries))
+	for _, entry := range m.history.entries {
+		candidates = append(candidates,
+			newSuggestion(entry.Title, entry.URL, "history", frecency(entry.VisitCount, entry.VisitTime)))
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > maxHistoryCandidates {
+		candidates = candidates[:maxHistoryCandidates]
+	}
+	m.historyCandidates = candidates
+	m.historyCandidatesVersion = m.history.version
+	return candidates
+}
+
+// bangSuggestions completes a "!" word at the end of input to the bangs
+// of matching engines, keeping the rest of the input
+func (m *model) bangSuggestions(input string) []Suggestion {
+	start := strings.LastIndexByte(input, ' ') + 1
+	bang, ok := strings.CutPrefix(input[start:], "!")
+	if !ok {
+		return nil
+	}
+	bang = strings.ToLower(bang)
+	var matches []Suggestion
+	for _, engine := range m.searchEngines() {
+		if engine.Keyword == "" || !strings.HasPrefix(strings.ToLower(engine.Keyword), bang) {
+			continue
+		}
+		matches = append(matches, newSuggestion(engine.Name, input[:start]+"!"+engine.Keyword+" ", "engine", 0))
+		if len(matches) == maxSuggestions {
+			break
+		}
+	}
+	return matches
+}
+
+// Recompute the dropdown for the current URL bar contents
+func (m *model) updateSuggestions() {
+	query := strings.ToLower(strings.TrimSpace(m.urlInput.Value()))
+	if query == "" {
+		m.clearSuggestions()
+		return
+	}
+	if bangs := m.bangSuggestions(m.urlInput.Value()); len(bangs) > 0 {
+		m.suggestions = bangs
+		m.suggestionIndex = -1
+		m.urlInput.SetSuggestions(nil)
+		return
+	}
+
+	var matches []Suggestion
+	for _, candidate := range m.completionCandidates() {
+		urlScore, urlOK := fuzzyScore(query, candidate.matchURL)
+		titleScore, titleOK := fuzzyScore(query, candidate.matchTitle)
+		if !urlOK && !titleOK {
+			continue
+		}
+		matchScore := math.Max(urlScore, titleScore)
+		candidate.score = matchScore*10 + candidate.score
+		matches = append(matches, candidate)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+
+	m.suggestions = matches
+	m.suggestionIndex = -1
+
+	// Feed prefix matches to the textinput so it can show inline completion
+	urls := make([]string, 0, len(matches))
+	for _, s := range matches {
+		urls = append(urls, stripScheme(s.URL))
+	}
+	m.urlInput.SetSuggestions(urls)
+}
+
+func (m *model) clearSuggestions() {
+	m.suggestions = nil
+	m.suggestionIndex = -1
+	m.urlInput.SetSuggestions(nil)
+}
+
+// Handle arrow/tab keys while the dropdown is open. Returns true if consumed.
+func (m *model) handleSuggestionKey(msg tea.KeyMsg) bool {
+	if len(m.suggestions) == 0 {
+		return false
+	}
+
+	switch msg.String() {
+	case "down", "ctrl+n":
+		m.suggestionIndex = (m.suggestionIndex + 1) % len(m.suggestions)
+		return true
+	case "up", "ctrl+p":
+		if m.suggestionIndex <= 0 {
+			m.suggestionIndex = len(m.suggestions) - 1
+		} else {
+			m.suggestionIndex--
+		}
+		return true
+	case "tab":
+		index := m.suggestionIndex
+		if index < 0 {
+			index = 0
+		}
+		m.urlInput.SetValue(m.suggestions[index].URL)
+		m.urlInput.CursorEnd()
+		m.updateSuggestions()
+		return true
+	}
+	return false
+}
+
+// selectedSuggestion returns the highlighted dropdown entry, if any
+func (m *model) selectedSuggestion() *Suggestion {
+	if m.suggestionIndex < 0 || m.suggestionIndex >= len(m.suggestions) {
+		return nil
+	}
+	return &m.suggestions[m.suggestionIndex]
+}
+
+// Render the dropdown shown below the URL bar
+func (m *model) renderSuggestions() string {
+	if len(m.suggestions) == 0 {
+		return ""
+	}
+
+	icons := map[string]string{
+		"history":  "🕘",
+		"bookmark": "⭐",
+		"tab":      "🗂️",
+		"keyword":  "⌘",
+		"engine":   "🔍",
+	}
+
+	var rows []string
+	for i, s := range m.suggestions {
+		title := s.Title
+		if title == "" {
+			title = stripScheme(s.URL)
+		}
+		if len(title) > 40 {
+			title = title[:37] + "..."
+		}
+		displayURL := stripScheme(s.URL)
+		if len(displayURL) > 50 {
+			displayURL = displayURL[:47] + "..."
+		}
+
+		row := fmt.Sprintf("%s %s", icons[s.Source], title)
+		switch s.Source {
+		case "keyword":
+		case "engine":
+			// Only the keyword or bang, not the query typed before a bang
+			fields := strings.Fields(s.URL)
+			row = fmt.Sprintf("%s %s  search %s", icons[s.Source], fields[len(fields)-1], s.Title)
+		default:
+			row += "  " + displayURL
+		}
+		if s.Source == "tab" {
+			row += fmt.Sprintf("  (switch to tab %d)", m.tabIndex(s.TabID)+1)
+		}
+
+		style := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("250")).
+			Background(lipgloss.Color("235")).
+			Padding(0, 1).
+			Width(m.width)
+		if i == m.suggestionIndex {
+			style = style.
+				Foreground(lipgloss.Color("255")).
+				Background(lipgloss.Color("62"))
+		}
+		rows = append(rows, style.Render(row))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
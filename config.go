@@ -3,7 +3,9 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 )
 
@@ -25,9 +27,30 @@ func applyEnvOverrides(config Config) Config {
 	if val := os.Getenv("BROWSER_SEARCH"); val != "" {
 		config.EnableSearch = val == "true"
 	}
+	if val := os.Getenv("BROWSER_PAGE_INDEX"); val != "" {
+		config.EnablePageIndex = val == "true"
+	}
 	if val := os.Getenv("BROWSER_STATUS_PANEL"); val != "" {
 		config.EnableStatusPanel = val == "true"
 	}
+	if val := os.Getenv("BROWSER_SEARCH_ENGINE"); val != "" {
+		config.DefaultEngine = val
+	}
+	if val := os.Getenv("BROWSER_REMOTE"); val != "" {
+		config.EnableRemote = val == "true"
+	}
+	if val := os.Getenv("BROWSER_REWRITES"); val != "" {
+		config.EnableRewrites = val == "true"
+	}
+	if val := os.Getenv("BROWSER_CLEAN_LINKS"); val != "" {
+		config.CleanLinks = val == "true"
+	}
+	if val := os.Getenv("BROWSER_DATA_DIR"); val != "" {
+		config.DataDir = val
+	}
+	if val := os.Getenv("BROWSER_CACHE_DIR"); val != "" {
+		config.CacheDir = val
+	}
 
 	return config
 }
@@ -43,34 +66,84 @@ func ParseFlags() Config {
 	flag.IntVar(&config.MaxTabs, "max-tabs", config.MaxTabs, "Maximum tabs")
 	flag.BoolVar(&config.EnableHistory, "history", config.EnableHistory, "Enable history")
 	flag.BoolVar(&config.EnableSearch, "search", config.EnableSearch, "Enable search")
+	flag.BoolVar(
+		&config.EnablePageIndex,
+		"page-index",
+		config.EnablePageIndex,
+		"Index visited pages for find-history",
+	)
 	flag.BoolVar(
 		&config.EnableStatusPanel,
 		"status",
 		config.EnableStatusPanel,
 		"Enable status panel",
 	)
+	flag.BoolVar(
+		&config.RestoreSession,
+		"restore",
+		config.RestoreSession,
+		"Restore tabs from the last session",
+	)
+	flag.StringVar(&config.Session, "session", config.Session, "Open a named session")
+	flag.StringVar(&config.DefaultEngine, "engine", config.DefaultEngine, "Default search engine keyword")
+	flag.BoolVar(&config.EnableRewrites, "rewrites", config.EnableRewrites, "Apply URL rewrite rules")
+	flag.BoolVar(&config.CleanLinks, "clean-links", config.CleanLinks, "Strip tracking parameters from links")
+	flag.StringVar(&config.DataDir, "data-dir", config.DataDir, "Directory for bookmarks, history and sessions")
+	flag.StringVar(&config.CacheDir, "cache-dir", config.CacheDir, "Directory for cached data")
+
+	var dump DumpOptions
+	flag.StringVar(&dump.URL, "dump", "", "Print the page at this URL to stdout and exit")
+	flag.StringVar(&dump.Format, "format", "", "Output format for -dump: ansi, md, text or json")
+	remote := flag.Bool("remote", false, "Send the command in the remaining arguments to a running browser")
+	jsonOutput := flag.Bool("json", false, "Print -dump output as JSON, same as -format json")
 
 	flag.Parse()
 
-	// If config file specified, load it (overrides flag defaults)
+	if *jsonOutput {
+		dump.Format = "json"
+	}
+	// With -dump, -reader picks reader mode extraction when given explicitly
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "reader" {
+			dump.Reader = config.EnableReaderMode
+		}
+	})
+
+	// Fall back to config.json in the config directory when it exists
+	if configFile == "" {
+		if path := filepath.Join(configDir(), "config.json"); fileExists(path) {
+			configFile = path
+		}
+	}
+
+	// The config file and environment go on top of the defaults, and flags
+	// given on the command line go on top of both
+	explicit := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
 	if configFile != "" {
-		if fileConfig, err := loadConfigFromFile(configFile); err == nil {
-			config = fileConfig
+		if err := loadConfigFromFile(configFile, &config); err != nil {
+			log.Printf("Error loading config %s: %v", configFile, err)
 		}
 	}
+	config = applyEnvOverrides(config)
+	for name, value := range explicit {
+		flag.Set(name, value)
+	}
+	config.Dump = dump
+	if *remote {
+		config.RemoteArgs = flag.Args()
+	}
 
 	return config
 }
 
-func loadConfigFromFile(filename string) (Config, error) {
+// loadConfigFromFile sets the fields of config that the file mentions
+func loadConfigFromFile(filename string, config *Config) error {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return DefaultConfig(), err
-	}
-
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return DefaultConfig(), err
+		return err
 	}
-	return config, nil
+	return json.Unmarshal(data, config)
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DumpOptions drive the headless -dump mode, which prints one page to
+// stdout instead of starting the TUI
+type DumpOptions struct {
+	URL    string
+	Reader bool
+	Format string // ansi, md, text or json; empty picks ansi for a terminal and text otherwise
+}
+
+var dumpFormats = []string{"ansi", "md", "text", "json"}
+
+// Exit codes for -dump
+const (
+	exitOK    = 0
+	exitError = 1 // the page couldn't be fetched or rendered
+	exitUsage = 2
+)
+
+// runDump fetches opts.URL, writes it to out and returns the exit code
+func runDump(opts DumpOptions, out io.Writer) int {
+	format := opts.Format
+	if format == "" {
+		format = "text"
+		if isTerminal(os.Stdout) {
+			format = "ansi"
+		}
+	}
+	if !slices.Contains(dumpFormats, format) {
+		fmt.Fprintf(os.Stderr, "bubbles: unknown format %q, want one of %s\n",
+			format, strings.Join(dumpFormats, ", "))
+		return exitUsage
+	}
+
+	if opts.URL == "" {
+		fmt.Fprintln(os.Stderr, "bubbles: -format and -json need -dump <url>")
+		return exitUsage
+	}
+	pageURL := opts.URL
+	if !strings.HasPrefix(pageURL, "http://") && !strings.HasPrefix(pageURL, "https://") {
+		pageURL = "https://" + pageURL
+	}
+	page, err := fetchPage(pageURL, opts.Reader)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bubbles: %s: %v\n", pageURL, err)
+		return exitError
+	}
+
+	var output string
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(page, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bubbles: %v\n", err)
+			return exitError
+		}
+		output = string(data) + "\n"
+	case "md":
+		output = page.Content + linkList(page.Links)
+	case "text":
+		output = markdownToText(page.Content + linkList(page.Links))
+	case "ansi":
+		output, err = renderWithStyle(page.Content + linkList(page.Links))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bubbles: %v\n", err)
+			return exitError
+		}
+	}
+
+	if _, err := io.WriteString(out, output); err != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+// linkList is the numbered Markdown list of links appended to a dump
+func linkList(links []PageLink) string {
+	if len(links) == 0 {
+		return ""
+	}
+	var list strings.Builder
+	list.WriteString("\n## Links\n\n")
+	for _, link := range links {
+		list.WriteString(fmt.Sprintf("- [%d] %s: %s\n", link.Number, link.Text, link.URL))
+	}
+	return list.String()
+}
+
+var (
+	textHeadingPattern  = regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+`)
+	textQuotePattern    = regexp.MustCompile(`(?m)^[ \t]*>[ \t]?`)
+	textEmphasisPattern = regexp.MustCompile("\\*\\*|__|`")
+)
+
+// markdownToText drops Markdown markup but keeps the line structure and
+// the [n] link numbers
+func markdownToText(markdown string) string {
+	text := markdownLinkPattern.ReplaceAllString(markdown, "$1")
+	text = textHeadingPattern.ReplaceAllString(text, "")
+	text = textQuotePattern.ReplaceAllString(text, "")
+	text = textEmphasisPattern.ReplaceAllString(text, "")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
+
+// isTerminal reports whether f is a character device rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
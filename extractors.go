@@ -2,22 +2,135 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/charmbracelet/lipgloss"
 )
 
-func extractContentWithLinks(doc *goquery.Document, baseURL string) (string, []Link, []ImageInfo) {
+// Extractor turns a parsed document into a Page. Registered and built-in
+// site extractors are tried before the generic ones.
+type Extractor interface {
+	Match(pageURL string) bool
+	Extract(doc *goquery.Document, pageURL string) Page
+}
+
+// Extractors added with registerExtractor, tried in order before the
+// built-in site extractors
+var registeredExtractors []Extractor
+
+func registerExtractor(e Extractor) {
+	registeredExtractors = append(registeredExtractors, e)
+}
+
+// extractorFor picks the extractor for pageURL
+func extractorFor(pageURL string, reader bool) Extractor {
+	for _, extractors := range [][]Extractor{registeredExtractors, builtinExtractors} {
+		for _, e := range extractors {
+			if e.Match(pageURL) {
+				return e
+			}
+		}
+	}
+	if reader {
+		return readerExtractor{}
+	}
+	return genericExtractor{}
+}
+
+// genericExtractor keeps every heading and paragraph on the page
+type genericExtractor struct{}
+
+func (genericExtractor) Match(string) bool { return true }
+
+func (genericExtractor) Extract(doc *goquery.Document, pageURL string) Page {
+	content, links, images, anchors := extractContentWithLinks(doc, pageURL)
+	return newPage(pageURL, "", content, links, images, anchors)
+}
+
+// readerExtractor keeps only the main article
+type readerExtractor struct{}
+
+func (readerExtractor) Match(string) bool { return true }
+
+func (readerExtractor) Extract(doc *goquery.Document, pageURL string) Page {
+	content, links, images, anchors := extractReaderContent(doc, pageURL)
+	return newPage(pageURL, "", content, links, images, anchors)
+}
+
+// SelectorExtractor is a site extractor described by CSS selectors, as
+// declared under "extractors" in config.json
+type SelectorExtractor struct {
+	Name   string   `json:"name"`
+	Hosts  []string `json:"hosts"`            // "*.example.com" also matches subdomains
+	Root   string   `json:"root"`             // element holding the content
+	Remove []string `json:"remove,omitempty"` // elements under Root to drop
+	Title  string   `json:"title,omitempty"`  // element holding the title, instead of <title>
+}
+
+func (e SelectorExtractor) Match(pageURL string) bool {
+	return matchHost(pageURL, e.Hosts)
+}
+
+func (e SelectorExtractor) Extract(doc *goquery.Document, pageURL string) Page {
+	root := doc.Find(e.Root).First()
+	if root.Length() == 0 {
+		return genericExtractor{}.Extract(doc, pageURL)
+	}
+	if len(e.Remove) > 0 {
+		root.Find(strings.Join(e.Remove, ", ")).Remove()
+	}
+	content, links, images, anchors := extractSelection(root, pageURL)
+	title := ""
+	if e.Title != "" {
+		title = strings.TrimSpace(doc.Find(e.Title).First().Text())
+	}
+	return newPage(pageURL, title, content, links, images, anchors)
+}
+
+// matchHost reports whether pageURL's host is one of hosts
+func matchHost(pageURL string, hosts []string) bool {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	for _, pattern := range hosts {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == strings.TrimPrefix(pattern, "www.") {
+			return true
+		}
+	}
+	return false
+}
+
+func extractContentWithLinks(
+	doc *goquery.Document,
+	baseURL string,
+) (string, []Link, []ImageInfo, []Anchor) {
+	return extractSelection(doc.Selection, baseURL)
+}
+
+// extractSelection is extractContentWithLinks for the part of a page under root
+func extractSelection(
+	root *goquery.Selection,
+	baseURL string,
+) (string, []Link, []ImageInfo, []Anchor) {
 	var content strings.Builder
 	var links []Link
 	var images []ImageInfo
+	var anchors []Anchor
 
 	linkCounter := 1
 	imageCounter := 1
 
 	// Extract images first
-	doc.Find("img").Each(func(i int, s *goquery.Selection) {
+	root.Find("img").Each(func(i int, s *goquery.Selection) {
 		src, _ := s.Attr("src")
 		alt, _ := s.Attr("alt")
 
@@ -53,7 +166,7 @@ func extractContentWithLinks(doc *goquery.Document, baseURL string) (string, []L
 	})
 
 	// Extract links (including those that contain images)
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+	root.Find("a").Each(func(i int, s *goquery.Selection) {
 		href, exists := s.Attr("href")
 		if !exists || href == "" {
 			return
@@ -90,13 +203,18 @@ func extractContentWithLinks(doc *goquery.Document, baseURL string) (string, []L
 	})
 
 	// Extract regular content (headers, paragraphs) with link numbers
-	doc.Find("h1, h2, h3, h4, h5, h6, p").Each(func(i int, s *goquery.Selection) {
+	blocks := "h1, h2, h3, h4, h5, h6, p"
+	targets := anchorTargets(root, blocks)
+	root.Find(blocks).Each(func(i int, s *goquery.Selection) {
 		tagName := goquery.NodeName(s)
 
 		// Process text content, replacing links with numbered references
 		text := processTextWithLinks(s, links)
 
 		if text != "" {
+			// Mark headings and id targets so fragments and the TOC can find them
+			text = addAnchor(&anchors, targets, s, strings.TrimSpace(s.Text())) + text
+
 			switch tagName {
 			case "h1":
 				content.WriteString(fmt.Sprintf("# %s\n\n", text))
@@ -131,13 +249,14 @@ func extractContentWithLinks(doc *goquery.Document, baseURL string) (string, []L
 		}
 	}
 
-	return content.String(), links, images
+	return content.String(), links, images, anchors
 }
 
-func extractReaderContent(doc *goquery.Document, baseURL string) (string, []Link) {
+func extractReaderContent(doc *goquery.Document, baseURL string) (string, []Link, []ImageInfo, []Anchor) {
 	var content strings.Builder
 	var links []Link
 	var images []ImageInfo
+	var anchors []Anchor
 
 	selectors := []string{
 		"article", "main", "[role='main']", ".content", ".post-content",
@@ -160,6 +279,7 @@ func extractReaderContent(doc *goquery.Document, baseURL string) (string, []Link
 		Remove()
 
 	linkCounter := 1
+	targets := anchorTargets(mainContent, "h1, h2, h3, h4, h5, h6, p, blockquote")
 	mainContent.Find("h1, h2, h3, h4, h5, h6, p, a, blockquote, ul, ol, li").
 		Each(func(i int, s *goquery.Selection) {
 			tagName := goquery.NodeName(s)
@@ -175,19 +295,19 @@ func extractReaderContent(doc *goquery.Document, baseURL string) (string, []Link
 
 			switch tagName {
 			case "h1":
-				content.WriteString(fmt.Sprintf("# %s\n\n", text))
+				content.WriteString(fmt.Sprintf("# %s%s\n\n", addAnchor(&anchors, targets, s, text), text))
 			case "h2":
-				content.WriteString(fmt.Sprintf("## %s\n\n", text))
+				content.WriteString(fmt.Sprintf("## %s%s\n\n", addAnchor(&anchors, targets, s, text), text))
 			case "h3":
-				content.WriteString(fmt.Sprintf("### %s\n\n", text))
+				content.WriteString(fmt.Sprintf("### %s%s\n\n", addAnchor(&anchors, targets, s, text), text))
 			case "h4", "h5", "h6":
-				content.WriteString(fmt.Sprintf("#### %s\n\n", text))
+				content.WriteString(fmt.Sprintf("#### %s%s\n\n", addAnchor(&anchors, targets, s, text), text))
 			case "p":
 				if len(text) > 20 {
-					content.WriteString(fmt.Sprintf("%s\n\n", text))
+					content.WriteString(fmt.Sprintf("%s%s\n\n", addAnchor(&anchors, targets, s, text), text))
 				}
 			case "blockquote":
-				content.WriteString(fmt.Sprintf("> %s\n\n", text))
+				content.WriteString(fmt.Sprintf("> %s%s\n\n", addAnchor(&anchors, targets, s, text), text))
 			case "ul", "ol":
 				s.Find("li").Each(func(j int, li *goquery.Selection) {
 					liText := strings.TrimSpace(li.Text())
@@ -246,7 +366,7 @@ func extractReaderContent(doc *goquery.Document, baseURL string) (string, []Link
 		}
 	}
 
-	return content.String(), links
+	return content.String(), links, images, anchors
 }
 
 func processTextWithLinks(s *goquery.Selection, links []Link) string {
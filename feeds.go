@@ -0,0 +1,587 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/net/html/charset"
+)
+
+// PageFeed is an RSS or Atom feed a page advertises with <link rel="alternate">
+type PageFeed struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type"` // rss or atom
+}
+
+var feedLinkTypes = map[string]string{
+	"application/rss+xml":  "rss",
+	"application/atom+xml": "atom",
+	"application/rdf+xml":  "rss",
+}
+
+// pageFeeds lists the feeds advertised in the document's <link> elements
+func pageFeeds(doc *goquery.Document, pageURL string) []PageFeed {
+	feeds := []PageFeed{}
+	doc.Find("link[rel][href]").Each(func(i int, s *goquery.Selection) {
+		kind, ok := feedLinkTypes[strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))]
+		if !ok || !slices.Contains(strings.Fields(strings.ToLower(s.AttrOr("rel", ""))), "alternate") {
+			return
+		}
+		feedURL := resolveURL(pageURL, s.AttrOr("href", ""))
+		if !strings.HasPrefix(feedURL, "http") ||
+			slices.ContainsFunc(feeds, func(f PageFeed) bool { return f.URL == feedURL }) {
+			return
+		}
+		feeds = append(feeds, PageFeed{URL: feedURL, Title: strings.TrimSpace(s.AttrOr("title", "")), Type: kind})
+	})
+	return feeds
+}
+
+// allowedFeeds drops feeds the filter lists block for pageURL
+func allowedFeeds(feeds []PageFeed, filter *ContentFilter, pageURL string) []PageFeed {
+	return slices.DeleteFunc(feeds, func(f PageFeed) bool {
+		return filter.Blocks(f.URL, pageURL, resourceOther)
+	})
+}
+
+// Version of the feeds.json layout
+const feedFormatVersion = 1
+
+// Feed is a subscription with the items seen in it. Read items are
+// forgotten once they drop out of the feed.
+type Feed struct {
+	URL     string     `json:"url"`
+	Title   string     `json:"title"`
+	Site    string     `json:"site,omitempty"` // the home page the feed links to
+	Added   time.Time  `json:"added"`
+	Checked time.Time  `json:"checked,omitempty"`
+	Error   string     `json:"error,omitempty"` // from the last refresh
+	Items   []FeedItem `json:"items"`
+}
+
+type FeedItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Published time.Time `json:"published,omitempty"`
+	Read      bool      `json:"read,omitempty"`
+}
+
+// feedRef points at an item in the feeds view
+type feedRef struct {
+	FeedURL string
+	ItemID  string
+}
+
+// newFeedStore returns the store for the subscriptions file at filename
+func newFeedStore(filename string) *jsonStore[Feed] {
+	return &jsonStore[Feed]{file: filename, key: "feeds", version: feedFormatVersion, name: "feeds"}
+}
+
+// updateFeeds applies change while holding the file lock, starting from
+// whatever another instance may have saved in the meantime
+func (m *model) updateFeeds(change func([]Feed) []Feed) {
+	m.feedStore.update(&m.feeds, change)
+}
+
+// feedIndex finds a subscription by URL, or -1
+func feedIndex(feeds []Feed, feedURL string) int {
+	return slices.IndexFunc(feeds, func(f Feed) bool { return f.URL == feedURL })
+}
+
+// mergeFeed takes in a fresh copy of the feed, keeping what was read and
+// unread items that have since dropped out of it
+func mergeFeed(feed *Feed, fetched parsedFeed) {
+	read := make(map[string]bool)
+	for _, item := range feed.Items {
+		read[item.ID] = item.Read
+	}
+	items := fetched.Items
+	for i := range items {
+		items[i].Read = read[items[i].ID]
+	}
+	for _, old := range feed.Items {
+		if !old.Read && !slices.ContainsFunc(items, func(item FeedItem) bool { return item.ID == old.ID }) {
+			items = append(items, old)
+		}
+	}
+	feed.Items = items
+	if fetched.Title != "" {
+		feed.Title = fetched.Title
+	}
+	if fetched.Site != "" {
+		feed.Site = fetched.Site
+	}
+}
+
+// unreadFeedItems lists unread items across all subscriptions, newest first
+func (m *model) unreadFeedItems() []feedRef {
+	type dated struct {
+		ref  feedRef
+		time time.Time
+	}
+	var items []dated
+	for _, feed := range m.feeds {
+		for _, item := range feed.Items {
+			if !item.Read {
+				items = append(items, dated{feedRef{feed.URL, item.ID}, item.Published})
+			}
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].time.After(items[j].time) })
+	refs := make([]feedRef, len(items))
+	for i, item := range items {
+		refs[i] = item.ref
+	}
+	return refs
+}
+
+// feedItem looks up a feed and item from the feeds view
+func (m *model) feedItem(ref feedRef) (*Feed, *FeedItem) {
+	return findFeedItem(m.feeds, ref)
+}
+
+// findFeedItem looks up the feed and item ref points at in feeds
+func findFeedItem(feeds []Feed, ref feedRef) (*Feed, *FeedItem) {
+	i := feedIndex(feeds, ref.FeedURL)
+	if i < 0 {
+		return nil, nil
+	}
+	feed := &feeds[i]
+	for j := range feed.Items {
+		if feed.Items[j].ID == ref.ItemID {
+			return feed, &feed.Items[j]
+		}
+	}
+	return feed, nil
+}
+
+// markFeedItems marks the referenced items read
+func (m *model) markFeedItems(refs []feedRef) {
+	m.updateFeeds(func(feeds []Feed) []Feed {
+		for _, ref := range refs {
+			if _, item := findFeedItem(feeds, ref); item != nil {
+				item.Read = true
+			}
+		}
+		return feeds
+	})
+}
+
+// parsedFeed is a feed as downloaded, before merging into a subscription
+type parsedFeed struct {
+	Title string
+	Site  string
+	Items []FeedItem
+}
+
+// The elements shared by RSS 2.0, RSS 1.0 and Atom. Only local names are
+// matched, so namespaced variants like dc:date land in the same fields.
+type xmlFeed struct {
+	XMLName xml.Name
+	Title   string    `xml:"title"`
+	Links   []xmlLink `xml:"link"`
+	Entries []xmlItem `xml:"entry"` // Atom
+	Items   []xmlItem `xml:"item"`  // RSS 1.0 keeps items outside the channel
+	Channel struct {
+		Title string    `xml:"title"`
+		Links []xmlLink `xml:"link"`
+		Items []xmlItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type xmlItem struct {
+	Title     string    `xml:"title"`
+	Links     []xmlLink `xml:"link"`
+	GUID      string    `xml:"guid"`
+	ID        string    `xml:"id"`
+	PubDate   string    `xml:"pubDate"`
+	Date      string    `xml:"date"`
+	Published string    `xml:"published"`
+	Updated   string    `xml:"updated"`
+}
+
+// xmlLink is an RSS <link>url</link> or an Atom <link href="url"/>
+type xmlLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Date layouts seen in feeds, tried in order
+var feedDateLayouts = []string{
+	time.RFC1123Z, time.RFC1123, time.RFC3339, time.RFC3339Nano,
+	"Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", "Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700", "2006-01-02T15:04:05", "2006-01-02",
+	time.RFC822Z, time.RFC822,
+}
+
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// pageLink picks the link to the web page from an RSS or Atom link list
+func pageLink(links []xmlLink, baseURL string) string {
+	for _, link := range links {
+		if text := strings.TrimSpace(link.Text); text != "" {
+			return resolveURL(baseURL, text)
+		}
+		if link.Href != "" && (link.Rel == "" || link.Rel == "alternate") {
+			return resolveURL(baseURL, link.Href)
+		}
+	}
+	return ""
+}
+
+// parseFeed reads an RSS or Atom document
+func parseFeed(r io.Reader, feedURL string) (parsedFeed, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	var doc xmlFeed
+	if err := decoder.Decode(&doc); err != nil {
+		return parsedFeed{}, fmt.Errorf("not a feed: %v", err)
+	}
+
+	var feed parsedFeed
+	var items []xmlItem
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "feed":
+		feed.Title, feed.Site, items = doc.Title, pageLink(doc.Links, feedURL), doc.Entries
+	case "rss", "rdf":
+		feed.Title, feed.Site = doc.Channel.Title, pageLink(doc.Channel.Links, feedURL)
+		items = append(doc.Channel.Items, doc.Items...)
+	default:
+		return parsedFeed{}, fmt.Errorf("not a feed: <%s> document", doc.XMLName.Local)
+	}
+	feed.Title = collapse(feed.Title)
+
+	for _, item := range items {
+		parsed := FeedItem{
+			Title: collapse(item.Title),
+			Link:  cleanLink(pageLink(item.Links, feedURL)),
+		}
+		for _, date := range []string{item.Published, item.PubDate, item.Date, item.Updated} {
+			if parsed.Published = parseFeedDate(date); !parsed.Published.IsZero() {
+				break
+			}
+		}
+		for _, id := range []string{item.GUID, item.ID, parsed.Link, parsed.Title} {
+			if id = strings.TrimSpace(id); id != "" {
+				parsed.ID = id
+				break
+			}
+		}
+		if parsed.ID == "" || parsed.Link == "" {
+			continue
+		}
+		if parsed.Title == "" {
+			parsed.Title = stripScheme(parsed.Link)
+		}
+		feed.Items = append(feed.Items, parsed)
+	}
+	return feed, nil
+}
+
+// Feeds larger than this are cut off rather than read into memory
+const maxFeedSize = 10 << 20
+
+func fetchFeed(feedURL string) (parsedFeed, error) {
+	req, err := http.NewRequest("GET", feedURL, nil)
+	if err != nil {
+		return parsedFeed{}, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return parsedFeed{}, fmt.Errorf("failed to fetch feed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return parsedFeed{}, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+	return parseFeed(io.LimitReader(resp.Body, maxFeedSize), feedURL)
+}
+
+type feedResult struct {
+	url  string
+	feed parsedFeed
+	err  error
+}
+
+// feedsFetchedMsg carries refreshed feeds back to Update. With subscribe
+// set, the single feed is a new subscription requested from tab tabID.
+type feedsFetchedMsg struct {
+	results   []feedResult
+	subscribe bool
+	tabID     int
+}
+
+type feedTickMsg time.Time
+
+// How many feeds are downloaded at once
+const feedFetchWorkers = 4
+
+// fetchFeeds downloads urls in the background
+func fetchFeeds(urls []string, subscribe bool, tabID int) tea.Cmd {
+	if len(urls) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		results := make([]feedResult, len(urls))
+		sem := make(chan struct{}, feedFetchWorkers)
+		var wg sync.WaitGroup
+		for i, feedURL := range urls {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				feed, err := fetchFeed(feedURL)
+				results[i] = feedResult{url: feedURL, feed: feed, err: err}
+			}()
+		}
+		wg.Wait()
+		return feedsFetchedMsg{results: results, subscribe: subscribe, tabID: tabID}
+	}
+}
+
+func (m *model) feedRefreshInterval() time.Duration {
+	return time.Duration(m.config.FeedRefreshInterval) * time.Minute
+}
+
+// refreshFeeds downloads every subscription, or with staleOnly just those
+// not checked within the refresh interval
+func (m *model) refreshFeeds(staleOnly bool) tea.Cmd {
+	if m.feedsRefreshing {
+		return nil
+	}
+	var urls []string
+	for _, feed := range m.feeds {
+		if !staleOnly || time.Since(feed.Checked) >= m.feedRefreshInterval() {
+			urls = append(urls, feed.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	m.feedsRefreshing = true
+	return fetchFeeds(urls, false, -1)
+}
+
+// scheduleFeedRefresh ticks the background refresh
+func (m *model) scheduleFeedRefresh() tea.Cmd {
+	if m.config.FeedRefreshInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.feedRefreshInterval(), func(t time.Time) tea.Msg {
+		return feedTickMsg(t)
+	})
+}
+
+func (m *model) handleFeedTick() (tea.Model, tea.Cmd) {
+	return m, tea.Batch(m.refreshFeeds(true), m.scheduleFeedRefresh())
+}
+
+// startupFeedRefresh catches up on feeds that went stale while the
+// browser was closed
+func (m *model) startupFeedRefresh() tea.Cmd {
+	if m.config.FeedRefreshInterval <= 0 {
+		return nil
+	}
+	return m.refreshFeeds(true)
+}
+
+func (m *model) handleFeedsFetched(msg feedsFetchedMsg) (tea.Model, tea.Cmd) {
+	if !msg.subscribe {
+		m.feedsRefreshing = false
+	}
+	var subscribed *Feed
+	var subscribeErr error
+	m.updateFeeds(func(feeds []Feed) []Feed {
+		for _, result := range msg.results {
+			i := feedIndex(feeds, result.url)
+			if i < 0 {
+				if !msg.subscribe {
+					continue // unsubscribed while it was being fetched
+				}
+				if result.err != nil {
+					subscribeErr = result.err
+					continue
+				}
+				feeds = append(feeds, Feed{URL: result.url, Title: stripScheme(result.url), Added: time.Now()})
+				i = len(feeds) - 1
+			}
+			feed := &feeds[i]
+			feed.Checked = time.Now()
+			feed.Error = ""
+			if result.err != nil {
+				feed.Error = result.err.Error()
+				continue
+			}
+			mergeFeed(feed, result.feed)
+			if msg.subscribe {
+				subscribed = feed
+			}
+		}
+		return feeds
+	})
+
+	if msg.subscribe {
+		if tab := m.tabByID(msg.tabID); tab != nil {
+			switch {
+			case subscribed != nil:
+				tab.setNotice(fmt.Sprintf("📡 Subscribed to %s (%d items)", subscribed.Title, len(subscribed.Items)))
+			case subscribeErr != nil:
+				tab.setError(fmt.Sprintf("Couldn't subscribe: %v", subscribeErr))
+			}
+		}
+	}
+	for i := range m.tabs {
+		if m.tabs[i].ShowFeeds {
+			m.showFeeds(&m.tabs[i], "")
+		}
+	}
+	return m, nil
+}
+
+// Handle "subscribe", "subscribe <n>" for the page's nth feed, and
+// "subscribe <feed url>"
+func (m *model) handleSubscribe(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	feedURL := ""
+	switch num, err := strconv.Atoi(args); {
+	case args == "" && len(activeTab.Feeds) > 0:
+		feedURL = activeTab.Feeds[0].URL
+	case args == "":
+		activeTab.setError("This page doesn't advertise a feed; try subscribe <feed url>")
+		return nil
+	case err == nil:
+		if num < 1 || num > len(activeTab.Feeds) {
+			activeTab.setError("Invalid feed number")
+			return nil
+		}
+		feedURL = activeTab.Feeds[num-1].URL
+	default:
+		feedURL = args
+		if !strings.HasPrefix(feedURL, "http://") && !strings.HasPrefix(feedURL, "https://") {
+			feedURL = "https://" + feedURL
+		}
+	}
+
+	if feedIndex(m.feeds, feedURL) >= 0 {
+		activeTab.setError(fmt.Sprintf("Already subscribed to %s", feedURL))
+		return nil
+	}
+	activeTab.setNotice("📡 Subscribing to " + feedURL)
+	return fetchFeeds([]string{feedURL}, true, activeTab.ID)
+}
+
+// Handle "feeds", "feeds refresh", "feeds read" and "feeds remove <n>"
+func (m *model) handleFeedsCommand(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	verb, rest, _ := strings.Cut(args, " ")
+	note := ""
+	var cmd tea.Cmd
+
+	switch verb {
+	case "":
+	case "refresh":
+		if cmd = m.refreshFeeds(false); cmd != nil {
+			note = "Checking feeds for new items..."
+		} else if m.feedsRefreshing {
+			note = "Already checking feeds"
+		}
+	case "read":
+		m.markFeedItems(m.unreadFeedItems())
+		note = "Marked everything read"
+	case "remove":
+		num, err := strconv.Atoi(strings.TrimSpace(rest))
+		if err != nil || num < 1 || num > len(m.feeds) {
+			activeTab.setError("Usage: feeds remove <subscription number>")
+			return nil
+		}
+		removed := m.feeds[num-1]
+		m.updateFeeds(func(feeds []Feed) []Feed {
+			if i := feedIndex(feeds, removed.URL); i >= 0 {
+				return slices.Delete(feeds, i, i+1)
+			}
+			return feeds
+		})
+		note = "Unsubscribed from " + removed.Title
+	default:
+		activeTab.setError("Usage: feeds [refresh|read|remove <n>]")
+		return nil
+	}
+
+	m.showFeeds(activeTab, note)
+	activeTab.setError("")
+	return cmd
+}
+
+// showFeeds puts the feeds view in tab
+func (m *model) showFeeds(tab *Tab, note string) {
+	tab.FeedResults = m.unreadFeedItems()
+	tab.clearOverlays()
+	tab.ShowFeeds = true
+	tab.ReaderMode = false
+	tab.Display = m.renderFeeds(tab, note)
+	if tab == m.activeTabPtr() && m.ready {
+		m.viewport.SetContent(tab.Display)
+	}
+}
+
+// openFeedItem marks item n of the feeds view read and opens it in reader mode
+func (m *model) openFeedItem(num int, tab *Tab) tea.Cmd {
+	ref := tab.FeedResults[num-1]
+	_, item := m.feedItem(ref)
+	if item == nil {
+		tab.setError("That item is no longer in its feed")
+		return nil
+	}
+	link, title := item.Link, item.Title
+	m.markFeedItems([]feedRef{ref})
+
+	tab.updateLoading("Opening feed item...")
+	tab.Display = fmt.Sprintf("🔄 Opening: %s", title)
+	tab.navigateTo(link)
+	tab.ShowFeeds = false
+	tab.ReaderMode = true
+	tab.CurrentImage = nil
+	m.urlInput.SetValue("")
+	return fetchContentWithReaderMode(link, tab.ID)
+}
+
+// feedIndicator describes the tab's feeds for the status panel
+func (m *model) feedIndicator(tab *Tab) string {
+	for _, feed := range tab.Feeds {
+		if feedIndex(m.feeds, feed.URL) >= 0 {
+			return "subscribed"
+		}
+	}
+	if len(tab.Feeds) == 1 {
+		return "feed, type subscribe"
+	}
+	return fmt.Sprintf("%d feeds, type subscribe <n>", len(tab.Feeds))
+}
@@ -0,0 +1,748 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/net/html"
+)
+
+// ContentFilter blocks requests and hides page elements with rules from
+// Adblock Plus / EasyList filter lists. Rules are read once at startup;
+// only the whitelist changes afterwards.
+type ContentFilter struct {
+	lists      []filterList
+	blocking   ruleSet
+	exceptions ruleSet
+
+	// Element hiding rules that apply everywhere, looked up by the id,
+	// class, tag or attribute the hidden element must have
+	hideByID    map[string][]*hidingRule
+	hideByClass map[string][]*hidingRule
+	hideByTag   map[string][]*hidingRule
+	hideByAttr  map[string][]*hidingRule
+	hideOther   []*hidingRule
+	// Rules for particular sites, by domain
+	hideByDomain map[string][]*hidingRule
+	// Selectors not to hide on a domain; "" holds the generic exceptions
+	hideExceptions map[string]map[string]bool
+
+	configAllowed []string // whitelisted in config.json
+	allowed       []string // whitelisted with "filters allow"
+	allowedFile   string
+}
+
+// filterList is a loaded list file, for the filters view
+type filterList struct {
+	Path    string
+	Network int
+	Hiding  int
+	Err     error
+}
+
+// resourceType is a bit set of the request types in rule options
+type resourceType int
+
+const (
+	resourceImage resourceType = 1 << iota
+	resourceScript
+	resourceStylesheet
+	resourceFont
+	resourceMedia
+	resourceObject
+	resourceSubdocument
+	resourceXHR
+	resourceWebsocket
+	resourcePing
+	resourceOther
+	resourceDocument
+	resourcePopup
+	resourceElemHide    // exception only: no element hiding on the page
+	resourceGenericHide // exception only: no generic element hiding on the page
+)
+
+var resourceTypeNames = map[string]resourceType{
+	"image": resourceImage, "script": resourceScript, "stylesheet": resourceStylesheet, "css": resourceStylesheet,
+	"font": resourceFont, "media": resourceMedia, "object": resourceObject, "subdocument": resourceSubdocument,
+	"frame": resourceSubdocument, "xmlhttprequest": resourceXHR, "xhr": resourceXHR, "websocket": resourceWebsocket,
+	"ping": resourcePing, "other": resourceOther, "document": resourceDocument, "doc": resourceDocument,
+	"popup": resourcePopup, "elemhide": resourceElemHide, "ehide": resourceElemHide,
+	"generichide": resourceGenericHide, "ghide": resourceGenericHide,
+}
+
+// Rules without a type option apply to every request for a resource
+const defaultResourceTypes = resourceDocument - 1
+
+// networkRule matches request URLs. The pattern is lowercased and keeps
+// the ABP wildcards: * for any run of characters, ^ for a separator.
+type networkRule struct {
+	pattern      string
+	regex        *regexp.Regexp // for /regex/ rules
+	domainAnchor bool           // || rules match from the start of a host label
+	types        resourceType
+	thirdParty   int // 1 only third-party requests, -1 only first-party
+	domains      []string
+	notDomains   []string
+}
+
+// ruleSet indexes network rules by a token their URLs must contain
+type ruleSet struct {
+	byToken map[string][]*networkRule
+	other   []*networkRule
+}
+
+// hidingRule is one compiled element hiding selector
+type hidingRule struct {
+	selector   string
+	matcher    cascadia.Selector
+	notDomains []string
+}
+
+// filterRequest is a request being checked against the rules
+type filterRequest struct {
+	url        string // lowercased
+	pageHost   string
+	thirdParty bool
+	kind       resourceType
+}
+
+// The filter used by every fetch, loaded from the config at startup. Nil
+// when no lists are configured.
+var contentFilter *ContentFilter
+
+// loadContentFilter reads the configured filter lists and the saved whitelist
+func loadContentFilter(paths []string, whitelist []string, whitelistFile string) *ContentFilter {
+	if len(paths) == 0 {
+		return nil
+	}
+	f := &ContentFilter{
+		blocking:       ruleSet{byToken: make(map[string][]*networkRule)},
+		exceptions:     ruleSet{byToken: make(map[string][]*networkRule)},
+		hideByID:       make(map[string][]*hidingRule),
+		hideByClass:    make(map[string][]*hidingRule),
+		hideByTag:      make(map[string][]*hidingRule),
+		hideByAttr:     make(map[string][]*hidingRule),
+		hideByDomain:   make(map[string][]*hidingRule),
+		hideExceptions: make(map[string]map[string]bool),
+		configAllowed:  whitelist,
+		allowedFile:    whitelistFile,
+	}
+	for _, path := range paths {
+		list := filterList{Path: path}
+		list.Network, list.Hiding, list.Err = f.loadList(expandPath(path))
+		if list.Err != nil {
+			log.Printf("Error loading filter list %s: %v", path, list.Err)
+		}
+		f.lists = append(f.lists, list)
+	}
+	if data, err := os.ReadFile(whitelistFile); err == nil {
+		if err := json.Unmarshal(data, &f.allowed); err != nil {
+			log.Printf("Error reading filter whitelist: %v", err)
+		}
+	}
+	return f
+}
+
+// loadList adds the rules of one list file and counts them
+func (f *ContentFilter) loadList(path string) (network, hiding int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		if i := strings.Index(line, "#@#"); i >= 0 {
+			f.addHidingException(line[:i], line[i+3:])
+			hiding++
+		} else if i := strings.Index(line, "##"); i >= 0 {
+			if f.addHidingRule(line[:i], line[i+2:]) {
+				hiding++
+			}
+		} else if strings.Contains(line, "#?#") || strings.Contains(line, "#$#") || strings.Contains(line, "#%#") {
+			// Extended CSS and snippets need a scripting engine
+		} else if rule, exception := parseNetworkRule(line); rule != nil {
+			if exception {
+				f.exceptions.add(rule)
+			} else {
+				f.blocking.add(rule)
+			}
+			network++
+		}
+	}
+	return network, hiding, scanner.Err()
+}
+
+// parseNetworkRule parses a blocking or @@exception rule. Rules with
+// options we can't honor are dropped rather than applied too broadly.
+func parseNetworkRule(line string) (*networkRule, bool) {
+	exception := false
+	if rest, ok := strings.CutPrefix(line, "@@"); ok {
+		exception, line = true, rest
+	}
+	options := ""
+	if !(len(line) > 1 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/")) {
+		if i := strings.LastIndex(line, "$"); i >= 0 {
+			line, options = line[:i], line[i+1:]
+		}
+	}
+
+	rule := &networkRule{types: defaultResourceTypes}
+	var include, exclude resourceType
+	for _, option := range strings.Split(options, ",") {
+		option = strings.ToLower(strings.TrimSpace(option))
+		name, negated := strings.CutPrefix(option, "~")
+		switch {
+		case option == "":
+		case name == "third-party" || name == "3p":
+			rule.thirdParty = 1
+			if negated {
+				rule.thirdParty = -1
+			}
+		case name == "first-party" || name == "1p":
+			rule.thirdParty = -1
+			if negated {
+				rule.thirdParty = 1
+			}
+		case strings.HasPrefix(option, "domain="):
+			rule.domains, rule.notDomains = parseDomains(strings.TrimPrefix(option, "domain="), "|")
+		case option == "match-case" || option == "important":
+		default:
+			kind, ok := resourceTypeNames[name]
+			if !ok {
+				return nil, false
+			}
+			if negated {
+				exclude |= kind
+			} else {
+				include |= kind
+			}
+		}
+	}
+	if include != 0 {
+		rule.types = include
+	}
+	rule.types &^= exclude
+
+	if len(line) > 1 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/") {
+		regex, err := regexp.Compile("(?i)" + line[1:len(line)-1])
+		if err != nil {
+			return nil, false
+		}
+		rule.regex = regex
+		return rule, exception
+	}
+
+	pattern := strings.ToLower(line)
+	if rest, ok := strings.CutPrefix(pattern, "||"); ok {
+		rule.domainAnchor, pattern = true, rest
+	} else if rest, ok := strings.CutPrefix(pattern, "|"); ok {
+		pattern = rest
+	} else if !strings.HasPrefix(pattern, "*") {
+		pattern = "*" + pattern
+	}
+	if rest, ok := strings.CutSuffix(pattern, "|"); ok {
+		pattern = rest
+	} else if !strings.HasSuffix(pattern, "*") {
+		pattern += "*"
+	}
+	rule.pattern = pattern
+	return rule, exception
+}
+
+// parseDomains splits a domain list into included and ~excluded domains
+func parseDomains(list, sep string) (include, exclude []string) {
+	for _, domain := range strings.Split(list, sep) {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if excluded, ok := strings.CutPrefix(domain, "~"); ok {
+			exclude = append(exclude, excluded)
+		} else if domain != "" {
+			include = append(include, domain)
+		}
+	}
+	return include, exclude
+}
+
+func (s *ruleSet) add(rule *networkRule) {
+	if token := ruleToken(rule.pattern); token != "" {
+		s.byToken[token] = append(s.byToken[token], rule)
+	} else {
+		s.other = append(s.other, rule)
+	}
+}
+
+// match returns whether any rule in the set matches req
+func (s *ruleSet) match(req filterRequest) bool {
+	for _, rule := range s.other {
+		if rule.matches(req) {
+			return true
+		}
+	}
+	for _, token := range urlTokens(req.url) {
+		for _, rule := range s.byToken[token] {
+			if rule.matches(req) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (rule *networkRule) matches(req filterRequest) bool {
+	if rule.types&req.kind == 0 ||
+		(rule.thirdParty == 1 && !req.thirdParty) || (rule.thirdParty == -1 && req.thirdParty) ||
+		!domainMatches(req.pageHost, rule.domains, rule.notDomains) {
+		return false
+	}
+	if rule.regex != nil {
+		return rule.regex.MatchString(req.url)
+	}
+	if !rule.domainAnchor {
+		return globMatch(rule.pattern, req.url)
+	}
+	// || matches from the start of the host or any label in it
+	start := strings.Index(req.url, "://")
+	if start < 0 {
+		return false
+	}
+	start += 3
+	end := len(req.url)
+	if i := strings.IndexAny(req.url[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+	for i := start; i < end; i++ {
+		if (i == start || req.url[i-1] == '.') && globMatch(rule.pattern, req.url[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches s against an ABP pattern: * is any run of characters,
+// ^ a separator or the end of s
+func globMatch(pattern, s string) bool {
+	p, i := 0, 0
+	star, starI := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, starI = p, i
+			p++
+		case p < len(pattern) && (pattern[p] == s[i] || (pattern[p] == '^' && isSeparator(s[i]))):
+			p++
+			i++
+		case star >= 0:
+			p = star + 1
+			starI++
+			i = starI
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && (pattern[p] == '*' || pattern[p] == '^') {
+		p++
+	}
+	return p == len(pattern)
+}
+
+func isSeparator(c byte) bool {
+	return !isTokenChar(c) && c != '_' && c != '-' && c != '.'
+}
+
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '%'
+}
+
+// ruleToken picks the longest run of token characters a matching URL is
+// sure to contain whole
+func ruleToken(pattern string) string {
+	best := ""
+	for i := 0; i < len(pattern); {
+		if !isTokenChar(pattern[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(pattern) && isTokenChar(pattern[j]) {
+			j++
+		}
+		whole := (i == 0 || pattern[i-1] != '*') && (j == len(pattern) || pattern[j] != '*')
+		if whole && j-i > len(best) {
+			best = pattern[i:j]
+		}
+		i = j
+	}
+	return best
+}
+
+func urlTokens(u string) []string {
+	return strings.FieldsFunc(u, func(r rune) bool {
+		return r > 0x7f || !isTokenChar(byte(r))
+	})
+}
+
+// domainMatches reports whether host falls under the include list (or any
+// host when it's empty) and none of the excluded domains
+func domainMatches(host string, include, exclude []string) bool {
+	for _, domain := range exclude {
+		if hostUnder(host, domain) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, domain := range include {
+		if hostUnder(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostUnder(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// baseDomain approximates the registrable domain with the last two labels
+func baseDomain(host string) string {
+	labels := strings.Split(host, ".")
+	if len(labels) <= 2 {
+		return host
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+func newFilterRequest(resourceURL, pageURL string, kind resourceType) filterRequest {
+	pageHost := ""
+	if parsed, err := url.Parse(pageURL); err == nil {
+		pageHost = strings.ToLower(parsed.Hostname())
+	}
+	host := ""
+	if parsed, err := url.Parse(resourceURL); err == nil {
+		host = strings.ToLower(parsed.Hostname())
+	}
+	return filterRequest{
+		url:        strings.ToLower(resourceURL),
+		pageHost:   pageHost,
+		thirdParty: baseDomain(host) != baseDomain(pageHost),
+		kind:       kind,
+	}
+}
+
+// Blocks reports whether a request for resourceURL made by pageURL is blocked
+func (f *ContentFilter) Blocks(resourceURL, pageURL string, kind resourceType) bool {
+	if f == nil {
+		return false
+	}
+	req := newFilterRequest(resourceURL, pageURL, kind)
+	return f.blocking.match(req) && !f.exceptions.match(req)
+}
+
+// pageException reports whether an exception rule turns kind off for the page
+func (f *ContentFilter) pageException(pageURL string, kind resourceType) bool {
+	return f.exceptions.match(newFilterRequest(pageURL, pageURL, kind))
+}
+
+// forPage returns the filter to use for pageURL, or nil if the site is
+// whitelisted. It reads the whitelist, so call it from Update.
+func (f *ContentFilter) forPage(pageURL string) *ContentFilter {
+	if f == nil {
+		return nil
+	}
+	for _, host := range slices.Concat(f.configAllowed, f.allowed) {
+		if matchHost(pageURL, []string{"*." + strings.TrimPrefix(host, "*.")}) {
+			return nil
+		}
+	}
+	return f
+}
+
+func (f *ContentFilter) addHidingRule(domains, selector string) bool {
+	selector = strings.TrimSpace(selector)
+	// uBlock scriptlets and HTML filters
+	if strings.HasPrefix(selector, "+js(") || strings.HasPrefix(selector, "^") {
+		return false
+	}
+	matcher, err := cascadia.Compile(selector)
+	if err != nil {
+		// Procedural selectors like :-abp-has() aren't CSS
+		return false
+	}
+	include, exclude := parseDomains(domains, ",")
+	rule := &hidingRule{selector: selector, matcher: matcher, notDomains: exclude}
+	if len(include) > 0 {
+		for _, domain := range include {
+			f.hideByDomain[domain] = append(f.hideByDomain[domain], rule)
+		}
+		return true
+	}
+	switch kind, key := selectorKey(selector); kind {
+	case '#':
+		f.hideByID[key] = append(f.hideByID[key], rule)
+	case '.':
+		f.hideByClass[key] = append(f.hideByClass[key], rule)
+	case 't':
+		f.hideByTag[key] = append(f.hideByTag[key], rule)
+	case '[':
+		f.hideByAttr[key] = append(f.hideByAttr[key], rule)
+	default:
+		f.hideOther = append(f.hideOther, rule)
+	}
+	return true
+}
+
+func (f *ContentFilter) addHidingException(domains, selector string) {
+	include, _ := parseDomains(domains, ",")
+	if len(include) == 0 {
+		include = []string{""}
+	}
+	for _, domain := range include {
+		if f.hideExceptions[domain] == nil {
+			f.hideExceptions[domain] = make(map[string]bool)
+		}
+		f.hideExceptions[domain][strings.TrimSpace(selector)] = true
+	}
+}
+
+// selectorKey finds the id, class, tag or attribute name that an element
+// must have to match selector, so generic rules can be looked up per
+// element instead of each being run over the whole page
+func selectorKey(selector string) (byte, string) {
+	// The rightmost compound selector is the one the element matches
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 0 && (c == ',' || c == '\\'):
+			return 0, ""
+		case depth == 0 && (c == ' ' || c == '>' || c == '+' || c == '~'):
+			start = i + 1
+		}
+	}
+	compound := selector[start:]
+
+	depth = 0
+	for i := 0; i < len(compound); i++ {
+		switch c := compound[i]; {
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 0 && (c == '#' || c == '.'):
+			j := i + 1
+			for j < len(compound) && isIdentChar(compound[j]) {
+				j++
+			}
+			if j > i+1 {
+				return c, compound[i+1 : j]
+			}
+		}
+	}
+	j := 0
+	for j < len(compound) && isIdentChar(compound[j]) {
+		j++
+	}
+	if j > 0 {
+		return 't', strings.ToLower(compound[:j])
+	}
+	if strings.HasPrefix(compound, "[") {
+		j = 1
+		for j < len(compound) && isIdentChar(compound[j]) {
+			j++
+		}
+		if j > 1 {
+			return '[', strings.ToLower(compound[1:j])
+		}
+	}
+	return 0, ""
+}
+
+func isIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '_' || c >= 0x80
+}
+
+// Apply removes hidden elements and blocked images from doc before it is
+// extracted and returns how many it removed
+func (f *ContentFilter) Apply(doc *goquery.Document, pageURL string) int {
+	if f == nil || f.pageException(pageURL, resourceDocument) {
+		return 0
+	}
+	blocked := 0
+	if !f.pageException(pageURL, resourceElemHide) {
+		blocked += f.hideElements(doc, pageURL, !f.pageException(pageURL, resourceGenericHide))
+	}
+	doc.Find("img[src]").Each(func(i int, img *goquery.Selection) {
+		src := resolveURL(pageURL, cleanURL(img.AttrOr("src", "")))
+		if f.Blocks(src, pageURL, resourceImage) {
+			img.Remove()
+			blocked++
+		}
+	})
+	return blocked
+}
+
+func (f *ContentFilter) hideElements(doc *goquery.Document, pageURL string, generic bool) int {
+	host := ""
+	if parsed, err := url.Parse(pageURL); err == nil {
+		host = strings.ToLower(parsed.Hostname())
+	}
+	excepted := func(rule *hidingRule) bool {
+		if !domainMatches(host, nil, rule.notDomains) || f.hideExceptions[""][rule.selector] {
+			return true
+		}
+		for domain, selectors := range f.hideExceptions {
+			if domain != "" && selectors[rule.selector] && hostUnder(host, domain) {
+				return true
+			}
+		}
+		return false
+	}
+
+	hidden := make(map[*html.Node]bool)
+	var nodes []*html.Node
+	hide := func(node *html.Node, rule *hidingRule) {
+		if !hidden[node] && !excepted(rule) {
+			hidden[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	check := func(node *html.Node, rules []*hidingRule) {
+		for _, rule := range rules {
+			if rule.matcher.Match(node) {
+				hide(node, rule)
+			}
+		}
+	}
+
+	if generic {
+		doc.Find("*").Each(func(i int, s *goquery.Selection) {
+			node := s.Get(0)
+			check(node, f.hideByTag[node.Data])
+			for _, attr := range node.Attr {
+				check(node, f.hideByAttr[attr.Key])
+				switch attr.Key {
+				case "id":
+					check(node, f.hideByID[attr.Val])
+				case "class":
+					for _, class := range strings.Fields(attr.Val) {
+						check(node, f.hideByClass[class])
+					}
+				}
+			}
+		})
+		for _, rule := range f.hideOther {
+			for _, node := range rule.matcher.MatchAll(doc.Get(0)) {
+				hide(node, rule)
+			}
+		}
+	}
+	for domain, rules := range f.hideByDomain {
+		if !hostUnder(host, domain) {
+			continue
+		}
+		for _, rule := range rules {
+			for _, node := range rule.matcher.MatchAll(doc.Get(0)) {
+				hide(node, rule)
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		if node.Parent != nil {
+			node.Parent.RemoveChild(node)
+		}
+	}
+	return len(nodes)
+}
+
+// setAllowed adds or removes host from the saved whitelist
+func (f *ContentFilter) setAllowed(host string, allow bool) error {
+	f.allowed = slices.DeleteFunc(f.allowed, func(h string) bool { return h == host })
+	if allow {
+		f.allowed = append(f.allowed, host)
+	}
+	data, err := json.MarshalIndent(f.allowed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.allowedFile, data)
+}
+
+// Handle "filters" and "filters allow|block [host]"
+func (m *model) handleFiltersCommand(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	verb, host, _ := strings.Cut(args, " ")
+	host = strings.ToLower(strings.TrimSpace(host))
+	note := ""
+
+	switch verb {
+	case "":
+	case "allow", "block":
+		if contentFilter == nil {
+			activeTab.setError("No filter lists loaded")
+			return nil
+		}
+		if host == "" {
+			parsed, err := url.Parse(activeTab.URL)
+			if err != nil || parsed.Hostname() == "" {
+				activeTab.setError(fmt.Sprintf("Usage: filters %s <host>", verb))
+				return nil
+			}
+			host = strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+		}
+		if verb == "block" && slices.Contains(contentFilter.configAllowed, host) {
+			activeTab.setError(fmt.Sprintf("%s is whitelisted in config.json", host))
+			return nil
+		}
+		if err := contentFilter.setAllowed(host, verb == "allow"); err != nil {
+			activeTab.setError(fmt.Sprintf("Error saving filter whitelist: %v", err))
+			return nil
+		}
+		note = fmt.Sprintf("Filtering turned off on %s; reload to see the whole page", host)
+		if verb == "block" {
+			note = fmt.Sprintf("Filtering turned back on on %s", host)
+		}
+	default:
+		activeTab.setError("Usage: filters [allow|block] [host]")
+		return nil
+	}
+
+	activeTab.Display = m.renderFilters(note)
+	activeTab.clearOverlays()
+	activeTab.ReaderMode = false
+	activeTab.setError("")
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+	}
+	return nil
+}
@@ -5,15 +5,35 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	m.syncPanes()
+	// Start the tab bar spinner once something begins loading
+	if !m.spinning && m.anyTabLoading() {
+		m.spinning = true
+		cmd = tea.Batch(cmd, m.spinner.Tick)
+	}
+	return model, cmd
+}
+
+func (m *model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// Arrow keys drive the autocompletion dropdown while it is open
+		if m.handleSuggestionKey(msg) {
+			return m, nil
+		}
+		// and pick a search result while the search view is showing
+		if m.handleSearchKey(msg) {
+			return m, nil
+		}
 		// Let handleKeyMsg process special commands first
 		newModel, newCmd := m.handleKeyMsg(msg)
 		if newCmd != nil || newModel != m {
@@ -21,6 +41,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		// If not handled as special command, pass to viewport for scrolling
 		m.viewport, cmd = m.viewport.Update(msg)
+		m.syncViewState()
 		return m, cmd
 
 	case tea.WindowSizeMsg:
@@ -31,18 +52,60 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleSearchResults(msg)
 	case errorMsg:
 		return m.handleError(msg)
+	case sessionTickMsg:
+		return m.handleSessionTick()
+	case feedTickMsg:
+		return m.handleFeedTick()
+	case feedsFetchedMsg:
+		return m.handleFeedsFetched(msg)
+	case laterSavedMsg:
+		return m.handleLaterSaved(msg)
+	case spinner.TickMsg:
+		return m.handleSpinnerTick(msg)
+	case remoteMsg:
+		return m.handleRemote(msg)
 	}
 
 	m.urlInput, cmd = m.urlInput.Update(msg)
 	m.viewport, _ = m.viewport.Update(msg)
+	m.syncViewState()
 
 	return m, cmd
 }
 
+// syncViewState remembers the scroll position of the page being read so
+// back/forward and tab switches can return to it. Overlays are skipped.
+func (m *model) syncViewState() {
+	tab := m.activeTabPtr()
+	if tab == nil || !m.ready || tab.Status.Loading || tab.CurrentImage != nil || tab.overlayShown() {
+		return
+	}
+	tab.ScrollOffset = m.viewport.YOffset
+	if entry := tab.currentEntry(); entry != nil {
+		entry.YOffset = m.viewport.YOffset
+	}
+}
+
+// fetchEntry reloads the tab's current history entry in the mode it was
+// last viewed in and scrolls back to the saved position
+func (m *model) fetchEntry(tab *Tab) tea.Cmd {
+	entry := tab.currentEntry()
+	if entry == nil {
+		return nil
+	}
+	tab.ReaderMode = entry.ReaderMode
+	tab.ScrollOffset = entry.YOffset
+	tab.restoreScroll = true
+	if entry.ReaderMode {
+		return fetchContentWithReaderMode(entry.URL, tab.ID)
+	}
+	return fetchContentWithLinks(entry.URL, tab.ID)
+}
+
 // Handle key messages
 func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
-	case "ctrl+c", "esc":
+	case "ctrl+c":
 		return m, tea.Quit
 
 	case "ctrl+t":
@@ -54,6 +117,45 @@ func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "ctrl+w":
 		return m.handleCloseTab()
 
+	case "alt+u":
+		return m.handleReopenTab(len(m.closedTabs) - 1)
+
+	case "alt+left", "alt+right":
+		return m.handleMoveTab(msg.String() == "alt+right")
+
+	case "alt+d":
+		return m.handleDuplicateTab()
+
+	case "alt+p":
+		return m.handlePinTab()
+
+	case "alt+i":
+		return m.handlePrivateTab("")
+
+	case "alt+s":
+		m.openSplit(splitHorizontal)
+		return m, m.ensureTabLoaded()
+
+	case "alt+v":
+		m.openSplit(splitVertical)
+		return m, m.ensureTabLoaded()
+
+	case "alt+o":
+		m.focusPane(1 - m.focusedPane)
+		return m, m.ensureTabLoaded()
+
+	case "alt+=", "alt++":
+		m.resizePane(splitResizeStep)
+		return m, nil
+
+	case "alt+-":
+		m.resizePane(-splitResizeStep)
+		return m, nil
+
+	case "alt+x":
+		m.closePane(m.focusedPane)
+		return m, m.ensureTabLoaded()
+
 	case "ctrl+tab":
 		return m.handleNextTab()
 
@@ -82,73 +184,87 @@ func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "ctrl+s":
 		return m.handleFocusSearch()
 
+	case "ctrl+y":
+		return m, m.handleCopy("")
+
 	case "left":
 		return m.handleGoBack()
 
 	case "right":
 		return m.handleGoForward()
 
-	case "escape":
+	case "esc":
 		return m.handleEscape()
 
+	case "alt+n":
+		return m.handleSearchPage(1)
+
+	case "alt+b":
+		return m.handleSearchPage(-1)
+
 	case "ctrl+o", "ctrl+O":
 		return m.handleOpenImage()
+
+	case "[", "]":
+		// [[ and ]] typed into an empty URL bar jump between headings
+		if m.urlInput.Value() == msg.String() {
+			m.urlInput.SetValue("")
+			m.clearSuggestions()
+			if msg.String() == "[" {
+				return m.handleHeadingJump(-1)
+			}
+			return m.handleHeadingJump(1)
+		}
 	}
 
 	m.urlInput, _ = m.urlInput.Update(msg)
+	m.updateSuggestions()
 	return m, nil
 }
 
 func (m *model) handleFollowImageLink() (tea.Model, tea.Cmd) {
-	if m.currentImage != nil && m.currentImage.IsLinked && m.currentImage.LinkURL != "" {
-		currentImg := m.currentImage
-		m.currentImage = nil
-
-		activeTab := m.activeTabPtr()
-		if activeTab != nil {
-			m.updateLoading("Following image link...")
-			m.content = fmt.Sprintf("🔄 Following link: %s", currentImg.LinkURL)
-			activeTab.navigateTo(currentImg.LinkURL)
-			m.readerMode = false
-			activeTab.ReaderMode = false
-			m.urlInput.SetValue("")
-			return m, fetchContentWithLinks(currentImg.LinkURL, m.activeTab)
-		}
+	activeTab := m.activeTabPtr()
+	if activeTab == nil {
+		return m, nil
+	}
+	if currentImg := activeTab.CurrentImage; currentImg != nil && currentImg.IsLinked && currentImg.LinkURL != "" {
+		activeTab.CurrentImage = nil
+		activeTab.updateLoading("Following image link...")
+		activeTab.Display = fmt.Sprintf("🔄 Following link: %s", currentImg.LinkURL)
+		activeTab.navigateTo(currentImg.LinkURL)
+		activeTab.ReaderMode = false
+		m.urlInput.SetValue("")
+		return m, fetchContentWithLinks(currentImg.LinkURL, activeTab.ID)
 	}
 	return m, nil
 }
 
 // Command handlers
 func (m *model) handleNewTab() (tea.Model, tea.Cmd) {
+	activeTab := m.activeTabPtr()
 	if !m.config.EnableTabs {
-		m.content = "❌ Tabs are disabled"
-		m.setError("Tabs feature disabled")
+		activeTab.Display = "❌ Tabs are disabled"
+		activeTab.setError("Tabs feature disabled")
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
 		}
 		return m, nil
 	}
 	if len(m.tabs) >= m.config.MaxTabs {
-		m.content = fmt.Sprintf("❌ Maximum tabs (%d) reached", m.config.MaxTabs)
-		m.setError(fmt.Sprintf("Max tabs limit: %d", m.config.MaxTabs))
+		activeTab.Display = fmt.Sprintf("❌ Maximum tabs (%d) reached", m.config.MaxTabs)
+		activeTab.setError(fmt.Sprintf("Max tabs limit: %d", m.config.MaxTabs))
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
 		}
 		return m, nil
 	}
 
-	m.newTab("")
-	m.content = "🌐 New Tab"
-	m.links = []Link{}
-	m.images = []ImageInfo{}
-	m.readerMode = false
-	m.showHistory = false
-	m.showBookmarks = false
-	m.showSearch = false
+	m.switchTab(m.newTab(""))
+	activeTab = m.activeTabPtr()
+	activeTab.Display = "🌐 New Tab"
 	m.urlInput.SetValue("")
-	m.setError("")
 	if m.ready {
-		m.viewport.SetContent(m.content)
+		m.viewport.SetContent(activeTab.Display)
 	}
 	return m, nil
 }
@@ -157,34 +273,95 @@ func (m *model) handleCloseTab() (tea.Model, tea.Cmd) {
 	if len(m.tabs) <= 1 {
 		return m, nil
 	}
+	if activeTab := m.activeTabPtr(); activeTab.Pinned {
+		activeTab.setError("Tab is pinned; unpin it with alt+p before closing")
+		return m, nil
+	}
 
 	m.closeTab(m.activeTab)
+	if activeTab := m.activeTabPtr(); activeTab != nil && m.ready {
+		m.viewport.SetContent(activeTab.Display)
+		m.viewport.SetYOffset(activeTab.DisplayOffset)
+	}
+	return m, m.ensureTabLoaded()
+}
+
+// Reopen closedTabs[i], respecting the tab limit
+func (m *model) handleReopenTab(i int) (tea.Model, tea.Cmd) {
 	activeTab := m.activeTabPtr()
-	if activeTab != nil {
-		m.content = activeTab.Content
-		m.links = activeTab.Links
-		m.images = activeTab.Images
-		m.readerMode = activeTab.ReaderMode
-		m.setError("")
-		if m.ready {
-			m.viewport.SetContent(m.content)
-		}
+	if i < 0 || i >= len(m.closedTabs) {
+		activeTab.setError("No closed tab to reopen")
+		return m, nil
+	}
+	if len(m.tabs) >= m.config.MaxTabs {
+		activeTab.setError(fmt.Sprintf("Max tabs limit: %d", m.config.MaxTabs))
+		return m, nil
+	}
+	activeTab.ShowClosed = false
+	m.reopenTab(i)
+	return m, m.ensureTabLoaded()
+}
+
+// Move the active tab one place to the right, or to the left
+func (m *model) handleMoveTab(right bool) (tea.Model, tea.Cmd) {
+	dir := -1
+	if right {
+		dir = 1
+	}
+	if !m.shiftActiveTab(dir) {
+		m.activeTabPtr().setError("Tab can't move further")
+	}
+	return m, nil
+}
+
+func (m *model) handleDuplicateTab() (tea.Model, tea.Cmd) {
+	activeTab := m.activeTabPtr()
+	if !m.config.EnableTabs {
+		activeTab.setError("Tabs feature disabled")
+		return m, nil
 	}
+	if len(m.tabs) >= m.config.MaxTabs {
+		activeTab.setError(fmt.Sprintf("Max tabs limit: %d", m.config.MaxTabs))
+		return m, nil
+	}
+	m.switchTab(m.duplicateTab(m.activeTab))
+	return m, m.ensureTabLoaded()
+}
+
+func (m *model) handlePinTab() (tea.Model, tea.Cmd) {
+	m.togglePin(m.activeTab)
+	m.activeTabPtr().setError("")
 	return m, nil
 }
 
+// Show the tab list, filtered by a fuzzy query
+func (m *model) showTabList(query string) {
+	activeTab := m.activeTabPtr()
+	activeTab.TabResults = nil
+	for _, index := range m.filterTabs(query) {
+		activeTab.TabResults = append(activeTab.TabResults, m.tabs[index].ID)
+	}
+	activeTab.clearOverlays()
+	activeTab.ShowTabs = true
+	activeTab.Display = m.renderTabList(activeTab, query)
+	m.urlInput.SetValue("")
+	activeTab.setError("")
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+		m.viewport.GotoTop()
+	}
+}
+
 func (m *model) handleNextTab() (tea.Model, tea.Cmd) {
 	nextTab := (m.activeTab + 1) % len(m.tabs)
 	m.switchTab(nextTab)
-	m.setError("")
-	return m, nil
+	return m, m.ensureTabLoaded()
 }
 
 func (m *model) handlePrevTab() (tea.Model, tea.Cmd) {
 	prevTab := (m.activeTab - 1 + len(m.tabs)) % len(m.tabs)
 	m.switchTab(prevTab)
-	m.setError("")
-	return m, nil
+	return m, m.ensureTabLoaded()
 }
 
 func (m *model) handleTabSwitch(key string) (tea.Model, tea.Cmd) {
@@ -192,23 +369,57 @@ func (m *model) handleTabSwitch(key string) (tea.Model, tea.Cmd) {
 	tabIndex := tabNum - 1
 	if tabIndex >= 0 && tabIndex < len(m.tabs) && tabIndex < 9 {
 		m.switchTab(tabIndex)
-		m.setError("")
 	}
-	return m, nil
+	return m, m.ensureTabLoaded()
 }
 
 func (m *model) handleEnter() (tea.Model, tea.Cmd) {
-	input := strings.TrimSpace(m.urlInput.Value())
 	activeTab := m.activeTabPtr()
+	input := strings.TrimSpace(m.urlInput.Value())
 	if activeTab == nil {
 		return m, nil
 	}
 
+	// A highlighted dropdown entry replaces whatever was typed
+	if selected := m.selectedSuggestion(); selected != nil {
+		if selected.Source == "tab" {
+			index := m.tabIndex(selected.TabID)
+			m.clearSuggestions()
+			m.urlInput.SetValue("")
+			if index < 0 {
+				return m, nil
+			}
+			m.switchTab(index)
+			return m, m.ensureTabLoaded()
+		}
+		// An engine keyword or bang still needs its query
+		if selected.Source == "engine" {
+			m.urlInput.SetValue(selected.URL)
+			m.urlInput.CursorEnd()
+			m.clearSuggestions()
+			return m, nil
+		}
+		input = selected.URL
+	}
+	m.clearSuggestions()
+
+	// The table of contents and closed tab list only consume the number typed right after it
+	if _, err := strconv.Atoi(input); err != nil {
+		activeTab.ShowTOC = false
+		activeTab.ShowClosed = false
+		activeTab.ShowTabs = false
+	}
+
 	// Handle special commands
 	if cmd, handled := m.handleSpecialCommands(input, activeTab); handled {
 		return m, cmd
 	}
 
+	// Enter on an empty bar opens the highlighted search result
+	if input == "" && activeTab.ShowSearch && activeTab.SearchSelected >= 0 {
+		return m.handleNumberInput(activeTab.SearchSelected+1, activeTab)
+	}
+
 	// Handle numbers (links, images, etc.)
 	if num, err := strconv.Atoi(input); err == nil {
 		return m.handleNumberInput(num, activeTab)
@@ -219,89 +430,218 @@ func (m *model) handleEnter() (tea.Model, tea.Cmd) {
 }
 
 func (m *model) handleSpecialCommands(input string, activeTab *Tab) (tea.Cmd, bool) {
+	// Commands may be written vim-style with a leading colon
+	input = strings.TrimPrefix(input, ":")
+
+	if args, found := strings.CutPrefix(input, "session"); found &&
+		(args == "" || strings.HasPrefix(args, " ")) {
+		return m.handleSessionCommand(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "rewrites"); found &&
+		(args == "" || strings.HasPrefix(args, " ")) {
+		return m.handleRewritesCommand(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "filters"); found &&
+		(args == "" || strings.HasPrefix(args, " ")) {
+		return m.handleFiltersCommand(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "private"); found &&
+		(args == "" || strings.HasPrefix(args, " ")) {
+		_, cmd := m.handlePrivateTab(strings.TrimSpace(args))
+		return cmd, true
+	}
+	if args, found := strings.CutPrefix(input, "subscribe"); found &&
+		(args == "" || strings.HasPrefix(args, " ")) {
+		return m.handleSubscribe(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "feeds"); found &&
+		(args == "" || strings.HasPrefix(args, " ")) {
+		return m.handleFeedsCommand(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "later"); found &&
+		(args == "" || strings.HasPrefix(args, " ")) {
+		return m.handleLaterCommand(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "copy"); found &&
+		(args == "" || strings.HasPrefix(args, " ")) {
+		return m.handleCopy(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "history "); found {
+		return m.handleHistoryCommand(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "find-history "); found {
+		return m.handleFindHistory(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "cached "); found {
+		return m.handleCachedPage(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "bookmarks "); found {
+		return m.handleBookmarksCommand(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "tabs "); found {
+		m.showTabList(args)
+		return nil, true
+	}
+	if args, found := strings.CutPrefix(input, "op "); found {
+		return m.handleOpenInPane(strings.TrimSpace(args)), true
+	}
+	if args, found := strings.CutPrefix(input, "bg "); found {
+		return m.handleBackgroundTab(strings.TrimSpace(args)), true
+	}
+
 	switch input {
 
 	case "help", "?":
-		m.content = loadHelpContent()
-		m.showHistory = false
-		m.showBookmarks = false
-		m.showSearch = false
-		m.showImages = false
-		m.readerMode = false
+		activeTab.Display = loadHelpContent(m.paths.Config)
+		activeTab.clearOverlays()
+		activeTab.ReaderMode = false
+		m.urlInput.SetValue("")
+		activeTab.setError("")
+		if m.ready {
+			m.viewport.SetContent(activeTab.Display)
+		}
+		return nil, true
+
+	case "engines":
+		activeTab.Display = m.renderEngines()
+		activeTab.clearOverlays()
+		activeTab.ReaderMode = false
 		m.urlInput.SetValue("")
-		m.setError("")
+		activeTab.setError("")
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
 		}
 		return nil, true
 
+	case "next", "prev":
+		m.urlInput.SetValue("")
+		if input == "next" {
+			return m.searchPageCmd(1), true
+		}
+		return m.searchPageCmd(-1), true
+
 	case "history", "h":
 		if !m.config.EnableHistory {
-			m.content = "❌ History is disabled"
-			m.setError("History feature disabled")
+			activeTab.Display = "❌ History is disabled"
+			activeTab.setError("History feature disabled")
 			if m.ready {
-				m.viewport.SetContent(m.content)
+				m.viewport.SetContent(activeTab.Display)
 			}
 			return nil, true
 		}
-		m.showHistory = true
-		m.showBookmarks = false
-		m.showSearch = false
-		m.showImages = false
-		m.readerMode = false
-		m.content = m.renderHistory()
+		activeTab.clearOverlays()
+		activeTab.ShowHistory = true
+		activeTab.ReaderMode = false
+		activeTab.HistoryQuery = ""
+		activeTab.HistoryResults = m.history.Entries()
+		activeTab.Display = m.renderHistory(activeTab)
 		m.urlInput.SetValue("")
-		m.setError("")
+		activeTab.setError("")
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
 		}
 		return nil, true
 
 	case "bookmarks", "b", "B":
-		if !m.config.EnableBookmarks {
-			m.content = "❌ Bookmarks are disabled"
-			m.setError("Bookmarks feature disabled")
-			if m.ready {
-				m.viewport.SetContent(m.content)
-			}
+		return m.handleBookmarksCommand(""), true
+
+	case "images", "i":
+		activeTab.clearOverlays()
+		activeTab.ShowImages = true
+		activeTab.ReaderMode = false
+		activeTab.Display = m.renderImages()
+		m.urlInput.SetValue("")
+		activeTab.setError("")
+		if m.ready {
+			m.viewport.SetContent(activeTab.Display)
+		}
+		return nil, true
+
+	case "toc", "t":
+		if len(headings(activeTab.Anchors)) == 0 {
+			activeTab.setError("No headings on this page")
+			m.urlInput.SetValue("")
 			return nil, true
 		}
-		m.showBookmarks = true
-		m.showHistory = false
-		m.showSearch = false
-		m.showImages = false
-		m.readerMode = false
-		m.content = m.renderBookmarks()
+		activeTab.clearOverlays()
+		activeTab.ShowTOC = true
+		activeTab.Display = m.renderTOC()
 		m.urlInput.SetValue("")
-		m.setError("")
+		activeTab.setError("")
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
+			m.viewport.GotoTop()
 		}
 		return nil, true
 
-	case "images", "i":
-		m.showImages = true
-		m.showHistory = false
-		m.showBookmarks = false
-		m.showSearch = false
-		m.readerMode = false
-		m.content = m.renderImages()
+	case "[[":
+		m.urlInput.SetValue("")
+		m.handleHeadingJump(-1)
+		return nil, true
+
+	case "]]":
+		m.urlInput.SetValue("")
+		m.handleHeadingJump(1)
+		return nil, true
+
+	case "undo", "u":
+		m.urlInput.SetValue("")
+		_, cmd := m.handleReopenTab(len(m.closedTabs) - 1)
+		return cmd, true
+
+	case "tabs":
+		m.showTabList("")
+		return nil, true
+
+	case "pin":
+		m.urlInput.SetValue("")
+		m.handlePinTab()
+		return nil, true
+
+	case "dup":
+		m.urlInput.SetValue("")
+		_, cmd := m.handleDuplicateTab()
+		return cmd, true
+
+	case "tab-left", "tab-right":
+		m.urlInput.SetValue("")
+		m.handleMoveTab(input == "tab-right")
+		return nil, true
+
+	case "split", "vsplit":
+		m.urlInput.SetValue("")
+		if input == "split" {
+			m.openSplit(splitHorizontal)
+		} else {
+			m.openSplit(splitVertical)
+		}
+		return nil, true
+
+	case "only":
 		m.urlInput.SetValue("")
-		m.setError("")
+		m.closePane(1 - m.focusedPane)
+		return nil, true
+
+	case "undo-list":
+		activeTab.clearOverlays()
+		activeTab.ShowClosed = true
+		activeTab.Display = m.renderClosedTabs()
+		m.urlInput.SetValue("")
+		activeTab.setError("")
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
+			m.viewport.GotoTop()
 		}
 		return nil, true
 
 	case "reader", "r", "R":
 		if len(activeTab.History) > 0 && activeTab.CurrentPos >= 0 {
-			m.updateLoading("Activating reader mode...")
-			m.content = "🔄 Activating reader mode..."
-			currentURL := activeTab.History[activeTab.CurrentPos]
-			m.readerMode = true
+			activeTab.updateLoading("Activating reader mode...")
+			activeTab.Display = "🔄 Activating reader mode..."
+			currentURL := activeTab.History[activeTab.CurrentPos].URL
 			activeTab.ReaderMode = true
 			m.urlInput.SetValue("")
-			return fetchContentWithReaderMode(currentURL, m.activeTab), true
+			return fetchContentWithReaderMode(currentURL, activeTab.ID), true
 		}
 	}
 
@@ -310,9 +650,9 @@ func (m *model) handleSpecialCommands(input string, activeTab *Tab) (tea.Cmd, bo
 	if numStr, found := strings.CutPrefix(lowerInput, "img"); found {
 		numStr = strings.TrimSpace(numStr)
 		if imgNum, err := strconv.Atoi(numStr); err == nil {
-			if imgNum > 0 && imgNum <= len(m.images) {
-				image := m.images[imgNum-1]
-				m.currentImage = &image
+			if imgNum > 0 && imgNum <= len(activeTab.Images) {
+				image := activeTab.Images[imgNum-1]
+				activeTab.CurrentImage = &image
 
 				var options string
 				if image.IsLinked && image.LinkURL != "" {
@@ -326,7 +666,7 @@ func (m *model) handleSpecialCommands(input string, activeTab *Tab) (tea.Cmd, bo
 					linkedInfo = fmt.Sprintf("\n🔗 Links to: %s", image.LinkURL)
 				}
 
-				m.content = fmt.Sprintf(
+				activeTab.Display = fmt.Sprintf(
 					"🖼️ Image %d: %s\n\nURL: %s\n\nAlt Text: %s\nType: %s%s\n\n%s",
 					imgNum,
 					image.AltText,
@@ -338,16 +678,16 @@ func (m *model) handleSpecialCommands(input string, activeTab *Tab) (tea.Cmd, bo
 				)
 				m.urlInput.SetValue("")
 				if m.ready {
-					m.viewport.SetContent(m.content)
+					m.viewport.SetContent(activeTab.Display)
 				}
 				return nil, true
 			}
 		} else {
-			m.content = "❌ Invalid image format. Use: img1, img2, etc."
-			m.setError("Invalid image format")
+			activeTab.Display = "❌ Invalid image format. Use: img1, img2, etc."
+			activeTab.setError("Invalid image format")
 			m.urlInput.SetValue("")
 			if m.ready {
-				m.viewport.SetContent(m.content)
+				m.viewport.SetContent(activeTab.Display)
 			}
 			return nil, true
 		}
@@ -356,63 +696,415 @@ func (m *model) handleSpecialCommands(input string, activeTab *Tab) (tea.Cmd, bo
 	return nil, false
 }
 
+// Handle "history <terms>", "history delete <n>" and "history clear"
+func (m *model) handleHistoryCommand(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	if !m.config.EnableHistory {
+		activeTab.Display = "❌ History is disabled"
+		activeTab.setError("History feature disabled")
+		if m.ready {
+			m.viewport.SetContent(activeTab.Display)
+		}
+		return nil
+	}
+
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 2 && (fields[0] == "delete" || fields[0] == "d"):
+		num, err := strconv.Atoi(fields[1])
+		if err != nil || num < 1 || num > len(activeTab.HistoryResults) {
+			activeTab.setError("Invalid history number")
+			return nil
+		}
+		m.history.Delete(activeTab.HistoryResults[num-1].URL)
+		m.pageIndex.Delete(activeTab.HistoryResults[num-1].URL)
+		activeTab.HistoryResults = m.history.Search(activeTab.HistoryQuery)
+		activeTab.setError("")
+	case len(fields) == 1 && fields[0] == "clear":
+		m.history.Clear()
+		m.pageIndex.Clear()
+		activeTab.HistoryQuery = ""
+		activeTab.HistoryResults = nil
+		activeTab.setError("")
+	default:
+		activeTab.HistoryQuery = args
+		activeTab.HistoryResults = m.history.Search(args)
+		activeTab.setError("")
+	}
+
+	activeTab.clearOverlays()
+	activeTab.ShowHistory = true
+	activeTab.ReaderMode = false
+	activeTab.Display = m.renderHistory(activeTab)
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+	}
+	return nil
+}
+
+// numberedURL returns the URL that typing num would open in the current view
+func (m *model) numberedURL(num int, activeTab *Tab) (string, bool) {
+	if num < 1 {
+		return "", false
+	}
+	switch {
+	case activeTab.ShowSearch:
+		if num <= len(activeTab.SearchResults) {
+			return activeTab.SearchResults[num-1].URL, true
+		}
+	case activeTab.ShowHistory:
+		if num <= len(activeTab.HistoryResults) {
+			return activeTab.HistoryResults[num-1].URL, true
+		}
+	case activeTab.ShowBookmarks:
+		if num <= len(activeTab.BookmarkResults) {
+			return activeTab.BookmarkResults[num-1], true
+		}
+	case activeTab.ShowFind:
+		if num <= len(activeTab.FindResults) {
+			return activeTab.FindResults[num-1].Page.URL, true
+		}
+	case activeTab.ShowFeeds:
+		if num <= len(activeTab.FeedResults) {
+			if _, item := m.feedItem(activeTab.FeedResults[num-1]); item != nil {
+				return item.Link, true
+			}
+		}
+	case activeTab.ShowLater:
+		if num <= len(activeTab.LaterResults) {
+			return laterScheme + activeTab.LaterResults[num-1], true
+		}
+	default:
+		if num <= len(activeTab.Links) {
+			return activeTab.Links[num-1].FullURL, true
+		}
+	}
+	return "", false
+}
+
+// shouldIndex reports whether the page just fetched into tab goes into the
+// page index. Pages kept out of history are kept out of the index too.
+func (m *model) shouldIndex(tab *Tab) bool {
+	return m.config.EnablePageIndex && !tab.Private && !strings.HasPrefix(tab.URL, "help://") &&
+		!strings.HasPrefix(tab.URL, laterScheme) && !m.history.isExcluded(tab.URL)
+}
+
+// Handle "find-history <terms>": search the text of visited pages
+func (m *model) handleFindHistory(query string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	if !m.config.EnablePageIndex {
+		activeTab.setError("Page index disabled")
+		return nil
+	}
+	if query == "" {
+		activeTab.setError("Usage: find-history <terms>")
+		return nil
+	}
+	activeTab.FindQuery = query
+	activeTab.FindResults = m.pageIndex.Search(query)
+	activeTab.clearOverlays()
+	activeTab.ShowFind = true
+	activeTab.Display = m.renderFindResults(activeTab)
+	activeTab.setError("")
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+		m.viewport.GotoTop()
+	}
+	return nil
+}
+
+// Handle "cached <n>": show the stored copy of a find-history result
+func (m *model) handleCachedPage(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	num, err := strconv.Atoi(args)
+	if err != nil || num < 1 || num > len(activeTab.FindResults) {
+		activeTab.setError("Usage: cached <result number>")
+		return nil
+	}
+	page := activeTab.FindResults[num-1].Page
+	banner := fmt.Sprintf("> 📦 Cached snapshot of %s from %s. Type `%d` in the results to load the live page.\n\n",
+		page.URL, page.Fetched.Format("Jan 2, 2006 15:04"), num)
+	display, err := renderWithStyle(banner + page.Markdown)
+	if err != nil {
+		display = banner + page.Markdown
+	}
+	activeTab.ShowFind = false
+	activeTab.Display = display
+	activeTab.setError("")
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+		m.viewport.GotoTop()
+	}
+	return nil
+}
+
+// Handle "bg <n>": open link n in a new tab that loads without being switched to
+func (m *model) handleBackgroundTab(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	if !m.config.EnableTabs {
+		activeTab.setError("Tabs feature disabled")
+		return nil
+	}
+	if len(m.tabs) >= m.config.MaxTabs {
+		activeTab.setError(fmt.Sprintf("Max tabs limit: %d", m.config.MaxTabs))
+		return nil
+	}
+	num, err := strconv.Atoi(args)
+	if err != nil {
+		activeTab.setError("Usage: bg <link number>")
+		return nil
+	}
+	url, ok := m.numberedURL(num, activeTab)
+	if !ok {
+		activeTab.setError("Invalid link number")
+		return nil
+	}
+
+	private := activeTab.Private
+	tab := &m.tabs[m.newTab(url)]
+	tab.Private = private
+	tab.updateLoading("Loading in background...")
+	// newTab may have reallocated m.tabs, so don't reuse activeTab
+	m.activeTabPtr().setError("")
+	return fetchContentWithLinks(url, tab.ID)
+}
+
+// Handle "bookmarks <filter>" and the bookmark editing subcommands, which
+// take a number from the bookmarks view
+func (m *model) handleBookmarksCommand(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	if !m.config.EnableBookmarks {
+		activeTab.Display = "❌ Bookmarks are disabled"
+		activeTab.setError("Bookmarks feature disabled")
+		if m.ready {
+			m.viewport.SetContent(activeTab.Display)
+		}
+		return nil
+	}
+
+	// Numbers refer to the full list unless the bookmarks view is open
+	if !activeTab.ShowBookmarks {
+		m.refreshBookmarks()
+		activeTab.BookmarkQuery = ""
+		activeTab.BookmarkResults = m.filterBookmarks("")
+	}
+
+	verb, rest, _ := strings.Cut(args, " ")
+	numStr, value, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	value = strings.TrimSpace(value)
+	editing := map[string]bool{
+		"delete": true, "d": true, "rename": true, "move": true,
+		"tag": true, "untag": true, "describe": true,
+	}[verb]
+
+	switch verb {
+	case "import":
+		added, skipped, err := m.importBookmarks(strings.TrimSpace(rest))
+		if err != nil {
+			activeTab.setError(fmt.Sprintf("Import failed: %v", err))
+			return nil
+		}
+		activeTab.BookmarkQuery = ""
+		activeTab.BookmarkResults = m.filterBookmarks("")
+		m.showBookmarks(fmt.Sprintf("Imported %d bookmarks, skipped %d duplicates", added, skipped))
+		return nil
+	case "export":
+		if err := m.exportBookmarks(strings.TrimSpace(rest)); err != nil {
+			activeTab.setError(fmt.Sprintf("Export failed: %v", err))
+			return nil
+		}
+		m.showBookmarks(fmt.Sprintf("Exported %d bookmarks to %s", len(m.bookmarks), strings.TrimSpace(rest)))
+		return nil
+	}
+
+	if editing {
+		num, err := strconv.Atoi(numStr)
+		if err != nil || num < 1 || num > len(activeTab.BookmarkResults) {
+			activeTab.setError("Invalid bookmark number")
+			return nil
+		}
+		if verb == "rename" && value == "" {
+			activeTab.setError("Usage: bookmarks rename <n> <title>")
+			return nil
+		}
+		// Another instance may have changed the file, so go by URL
+		url := activeTab.BookmarkResults[num-1]
+		m.updateBookmarks(func(bookmarks []Bookmark) []Bookmark {
+			index := bookmarkIndex(bookmarks, url)
+			if index < 0 {
+				return bookmarks
+			}
+			bookmark := &bookmarks[index]
+			switch verb {
+			case "delete", "d":
+				return append(bookmarks[:index], bookmarks[index+1:]...)
+			case "rename":
+				bookmark.Title = value
+			case "move":
+				// "/" or nothing moves the bookmark back to the top level
+				bookmark.Folder = strings.Trim(value, "/")
+			case "tag":
+				bookmark.addTags(strings.Fields(value))
+			case "untag":
+				bookmark.removeTags(strings.Fields(value))
+			case "describe":
+				bookmark.Description = value
+			}
+			return bookmarks
+		})
+	} else {
+		activeTab.BookmarkQuery = args
+	}
+
+	activeTab.BookmarkResults = m.filterBookmarks(activeTab.BookmarkQuery)
+	m.showBookmarks("")
+	return nil
+}
+
+// showBookmarks opens the bookmarks view with an optional notice on top
+func (m *model) showBookmarks(notice string) {
+	activeTab := m.activeTabPtr()
+	activeTab.clearOverlays()
+	activeTab.ShowBookmarks = true
+	activeTab.ReaderMode = false
+	activeTab.Display = m.renderBookmarks(activeTab, notice)
+	activeTab.setError("")
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+		m.viewport.GotoTop()
+	}
+}
+
 func (m *model) handleNumberInput(num int, activeTab *Tab) (tea.Model, tea.Cmd) {
-	if m.showSearch && num > 0 && num <= len(m.searchResults) {
-		result := m.searchResults[num-1]
-		m.updateLoading("Opening search result...")
-		m.content = fmt.Sprintf("🔄 Opening: %s", result.Title)
+	if activeTab.ShowTOC {
+		toc := headings(activeTab.Anchors)
+		m.urlInput.SetValue("")
+		if num > 0 && num <= len(toc) {
+			m.scrollToLine(activeTab, toc[num-1].Line)
+			activeTab.setError("")
+		} else {
+			activeTab.setError("Invalid heading number")
+		}
+		return m, nil
+
+	} else if activeTab.ShowTabs {
+		m.urlInput.SetValue("")
+		activeTab.ShowTabs = false
+		if num < 1 || num > len(activeTab.TabResults) || m.tabIndex(activeTab.TabResults[num-1]) < 0 {
+			activeTab.setError("Invalid tab number")
+			return m, nil
+		}
+		// Leave this tab showing its page rather than the list
+		activeTab.Display = activeTab.Content
+		if m.ready {
+			m.viewport.SetContent(activeTab.Display)
+			m.viewport.SetYOffset(activeTab.ScrollOffset)
+		}
+		m.switchTab(m.tabIndex(activeTab.TabResults[num-1]))
+		return m, m.ensureTabLoaded()
+
+	} else if activeTab.ShowClosed {
+		m.urlInput.SetValue("")
+		activeTab.ShowClosed = false
+		// The list is shown newest first
+		return m.handleReopenTab(len(m.closedTabs) - num)
+
+	} else if activeTab.ShowSearch && num > 0 && num <= len(activeTab.SearchResults) {
+		result := activeTab.SearchResults[num-1]
+		activeTab.searchReturn = result.URL
+		activeTab.updateLoading("Opening search result...")
+		activeTab.Display = fmt.Sprintf("🔄 Opening: %s", result.Title)
 		activeTab.navigateTo(result.URL)
-		m.showSearch = false
-		m.readerMode = false
-		m.currentImage = nil
+		activeTab.ShowSearch = false
+		activeTab.ReaderMode = false
+		activeTab.CurrentImage = nil
+		m.urlInput.SetValue("")
+		return m, fetchContentWithLinks(result.URL, activeTab.ID)
+
+	} else if activeTab.ShowHistory && num > 0 && num <= len(activeTab.HistoryResults) {
+		entry := activeTab.HistoryResults[num-1]
+		activeTab.updateLoading("Opening history entry...")
+		activeTab.Display = fmt.Sprintf("🔄 Opening: %s", entry.URL)
+		activeTab.navigateTo(entry.URL)
+		activeTab.ShowHistory = false
+		activeTab.ReaderMode = false
+		m.urlInput.SetValue("")
+		return m, fetchContentWithLinks(entry.URL, activeTab.ID)
+
+	} else if activeTab.ShowBookmarks && num > 0 && num <= len(activeTab.BookmarkResults) {
+		url := activeTab.BookmarkResults[num-1]
+		title := url
+		if index := bookmarkIndex(m.bookmarks, url); index >= 0 {
+			title = m.bookmarks[index].Title
+		}
+		activeTab.updateLoading("Opening bookmark...")
+		activeTab.Display = fmt.Sprintf("🔄 Opening bookmark: %s", title)
+		activeTab.navigateTo(url)
+		activeTab.ShowBookmarks = false
 		activeTab.ReaderMode = false
 		m.urlInput.SetValue("")
-		return m, fetchContentWithLinks(result.URL, m.activeTab)
-
-	} else if m.showBookmarks && num > 0 && num <= len(m.bookmarks) {
-		bookmark := m.bookmarks[num-1]
-		m.updateLoading("Opening bookmark...")
-		m.content = fmt.Sprintf("🔄 Opening bookmark: %s", bookmark.Title)
-		activeTab.navigateTo(bookmark.URL)
-		m.showBookmarks = false
-		m.readerMode = false
+		return m, fetchContentWithLinks(url, activeTab.ID)
+
+	} else if activeTab.ShowFind && num > 0 && num <= len(activeTab.FindResults) {
+		page := activeTab.FindResults[num-1].Page
+		activeTab.updateLoading("Opening page...")
+		activeTab.Display = fmt.Sprintf("🔄 Opening: %s", page.URL)
+		activeTab.navigateTo(page.URL)
+		activeTab.ShowFind = false
 		activeTab.ReaderMode = false
 		m.urlInput.SetValue("")
-		return m, fetchContentWithLinks(bookmark.URL, m.activeTab)
+		return m, fetchContentWithLinks(page.URL, activeTab.ID)
+
+	} else if activeTab.ShowFeeds && num > 0 && num <= len(activeTab.FeedResults) {
+		return m, m.openFeedItem(num, activeTab)
+
+	} else if activeTab.ShowLater && num > 0 && num <= len(activeTab.LaterResults) {
+		return m, m.openLaterItem(num, activeTab)
 
-	} else if num > 0 && num <= len(m.links) {
-		link := m.links[num-1]
+	} else if num > 0 && num <= len(activeTab.Links) {
+		link := activeTab.Links[num-1]
 
 		// Check if this link points to an image
 		if isImageURL(link.FullURL) {
-			m.content = fmt.Sprintf("🖼️ Image Link: %s\n\nURL: %s\n\nPress 'o' to open image externally",
+			activeTab.Display = fmt.Sprintf("🖼️ Image Link: %s\n\nURL: %s\n\nPress 'o' to open image externally",
 				link.Text, link.FullURL)
-			m.currentImage = &ImageInfo{
+			activeTab.CurrentImage = &ImageInfo{
 				URL:     link.FullURL,
 				AltText: link.Text,
 				Type:    getImageType(link.FullURL),
 			}
 			m.urlInput.SetValue("")
 			if m.ready {
-				m.viewport.SetContent(m.content)
+				m.viewport.SetContent(activeTab.Display)
 			}
 			return m, nil
 		}
 
-		m.updateLoading("Following link...")
-		m.content = fmt.Sprintf("🔄 Navigating to: %s", link.Text)
+		// Fragment links within the current page scroll instead of refetching
+		if fragment, same := sameDocument(activeTab.URL, link.FullURL); same {
+			m.urlInput.SetValue("")
+			m.jumpToFragment(activeTab, link.FullURL, fragment)
+			return m, nil
+		}
+
+		activeTab.updateLoading("Following link...")
+		activeTab.Display = fmt.Sprintf("🔄 Navigating to: %s", link.Text)
 		activeTab.navigateTo(link.FullURL)
-		m.readerMode = false
 		activeTab.ReaderMode = false
 		m.urlInput.SetValue("")
-		return m, fetchContentWithLinks(link.FullURL, m.activeTab)
+		return m, fetchContentWithLinks(link.FullURL, activeTab.ID)
 
 	} else {
-		m.content = fmt.Sprintf("❌ Invalid number. Available links: 1-%d", len(m.links))
-		m.setError("Invalid link number")
+		activeTab.Display = fmt.Sprintf("❌ Invalid number. Available links: 1-%d", len(activeTab.Links))
+		activeTab.setError("Invalid link number")
 		m.urlInput.SetValue("")
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
 		}
 	}
 
@@ -421,6 +1113,9 @@ func (m *model) handleNumberInput(num int, activeTab *Tab) (tea.Model, tea.Cmd)
 
 func (m *model) handleURLOrSearch(input string, activeTab *Tab) (tea.Model, tea.Cmd) {
 	if input != "" {
+		if engine, query, ok := m.explicitEngine(input); ok {
+			return m.search(engine, query, activeTab)
+		}
 		if strings.Contains(input, ".") ||
 			strings.HasPrefix(input, "http://") ||
 			strings.HasPrefix(input, "https://") {
@@ -428,63 +1123,85 @@ func (m *model) handleURLOrSearch(input string, activeTab *Tab) (tea.Model, tea.
 			if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 				url = "https://" + url
 			}
+			url = cleanLink(url)
 
 			// Check if this is an image URL
 			if isImageURL(url) {
-				m.content = fmt.Sprintf(
+				activeTab.Display = fmt.Sprintf(
 					"🖼️ Image URL detected: %s\n\nPress 'o' to open image externally",
 					url,
 				)
-				m.currentImage = &ImageInfo{
+				activeTab.CurrentImage = &ImageInfo{
 					URL:     url,
 					AltText: "Direct image link",
 					Type:    getImageType(url),
 				}
 				m.urlInput.SetValue("")
 				if m.ready {
-					m.viewport.SetContent(m.content)
+					m.viewport.SetContent(activeTab.Display)
 				}
 				return m, nil
 			}
 
-			m.updateLoading("Fetching page...")
-			m.content = "🔄 Loading..."
+			if fragment, same := sameDocument(activeTab.URL, url); same && activeTab.Content != "" {
+				m.urlInput.SetValue("")
+				m.jumpToFragment(activeTab, url, fragment)
+				return m, nil
+			}
+
+			activeTab.updateLoading("Fetching page...")
+			activeTab.Display = "🔄 Loading..."
 			activeTab.navigateTo(url)
-			m.showBookmarks = false
-			m.showHistory = false
-			m.showSearch = false
-			m.showImages = true
-			m.readerMode = false
+			activeTab.clearOverlays()
+			activeTab.ShowImages = true
 			activeTab.ReaderMode = false
 			m.urlInput.SetValue("")
-			return m, fetchContentWithLinks(url, m.activeTab)
+			return m, fetchContentWithLinks(url, activeTab.ID)
 		} else {
-			m.updateLoading("Searching...")
-			m.content = fmt.Sprintf("🔍 Searching for: %s", input)
-			m.searchQuery = input
-			m.showSearch = true
-			m.showBookmarks = false
-			m.showHistory = false
-			m.showImages = false
-			m.readerMode = true
-			activeTab.ReaderMode = false
-			m.urlInput.SetValue("")
-			return m, performSearch(input)
+			return m.search(m.defaultEngine(), input, activeTab)
 		}
 	}
 	return m, nil
 }
 
+// search runs query on engine. Engines with result selectors are scraped
+// into the search results view, the rest open their results page.
+func (m *model) search(engine SearchEngine, query string, activeTab *Tab) (tea.Model, tea.Cmd) {
+	m.urlInput.SetValue("")
+	if query == "" {
+		activeTab.setError(fmt.Sprintf("Nothing to search for on %s", engine.Name))
+		return m, nil
+	}
+	activeTab.clearOverlays()
+	activeTab.ReaderMode = false
+
+	if engine.Selectors == nil {
+		url := engine.searchURL(query)
+		activeTab.updateLoading(fmt.Sprintf("Searching %s...", engine.Name))
+		activeTab.Display = "🔄 Loading..."
+		activeTab.navigateTo(url)
+		activeTab.ShowImages = true
+		return m, fetchContentWithLinks(url, activeTab.ID)
+	}
+
+	activeTab.updateLoading(fmt.Sprintf("Searching %s...", engine.Name))
+	activeTab.Display = fmt.Sprintf("🔍 Searching %s for: %s", engine.Name, query)
+	activeTab.SearchQuery = query
+	activeTab.ShowSearch = true
+	return m, performSearch(engine, query, activeTab.ID)
+}
+
 func (m *model) handleReload() (tea.Model, tea.Cmd) {
 	activeTab := m.activeTabPtr()
 	if activeTab != nil && len(activeTab.History) > 0 && activeTab.CurrentPos >= 0 {
-		m.updateLoading("Reloading...")
-		m.content = "🔄 Reloading..."
-		currentURL := activeTab.History[activeTab.CurrentPos]
-		if m.readerMode {
-			return m, fetchContentWithReaderMode(currentURL, m.activeTab)
+		activeTab.updateLoading("Reloading...")
+		activeTab.Display = "🔄 Reloading..."
+		activeTab.restoreScroll = true
+		currentURL := activeTab.History[activeTab.CurrentPos].URL
+		if activeTab.ReaderMode {
+			return m, fetchContentWithReaderMode(currentURL, activeTab.ID)
 		}
-		return m, fetchContentWithLinks(currentURL, m.activeTab)
+		return m, fetchContentWithLinks(currentURL, activeTab.ID)
 	}
 	return m, nil
 }
@@ -492,44 +1209,44 @@ func (m *model) handleReload() (tea.Model, tea.Cmd) {
 func (m *model) handleReaderToggle() (tea.Model, tea.Cmd) {
 	activeTab := m.activeTabPtr()
 	if activeTab != nil && len(activeTab.History) > 0 && activeTab.CurrentPos >= 0 {
-		if m.readerMode {
-			m.readerMode = false
+		if activeTab.ReaderMode {
 			activeTab.ReaderMode = false
-			m.updateLoading("Loading original view...")
-			m.content = "🔄 Loading original view..."
-			currentURL := activeTab.History[activeTab.CurrentPos]
+			activeTab.updateLoading("Loading original view...")
+			activeTab.Display = "🔄 Loading original view..."
+			currentURL := activeTab.History[activeTab.CurrentPos].URL
 			m.urlInput.SetValue("")
-			return m, fetchContentWithLinks(currentURL, m.activeTab)
+			return m, fetchContentWithLinks(currentURL, activeTab.ID)
 		} else {
-			m.updateLoading("Activating reader mode...")
-			m.content = "🔄 Activating reader mode..."
-			currentURL := activeTab.History[activeTab.CurrentPos]
-			m.readerMode = true
+			activeTab.updateLoading("Activating reader mode...")
+			activeTab.Display = "🔄 Activating reader mode..."
+			currentURL := activeTab.History[activeTab.CurrentPos].URL
 			activeTab.ReaderMode = true
 			m.urlInput.SetValue("")
-			return m, fetchContentWithReaderMode(currentURL, m.activeTab)
+			return m, fetchContentWithReaderMode(currentURL, activeTab.ID)
 		}
 	}
 	return m, nil
 }
 
 func (m *model) handleBookmark() (tea.Model, tea.Cmd) {
+	activeTab := m.activeTabPtr()
 	if !m.config.EnableBookmarks {
-		m.content = "❌ Bookmarks are disabled"
-		m.setError("Bookmarks feature disabled")
+		activeTab.Display = "❌ Bookmarks are disabled"
+		activeTab.setError("Bookmarks feature disabled")
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
 		}
 		return m, nil
 	}
 
-	activeTab := m.activeTabPtr()
 	if activeTab != nil && len(activeTab.History) > 0 && activeTab.CurrentPos >= 0 {
-		currentURL := activeTab.History[activeTab.CurrentPos]
+		currentURL := cleanLink(activeTab.History[activeTab.CurrentPos].URL)
 		if !m.isBookmarked(currentURL) {
 			title := "Untitled"
-			if len(m.links) > 0 {
-				for _, link := range m.links {
+			if activeTab.Title != "" && activeTab.Title != "New Tab" {
+				title = activeTab.Title
+			} else if len(activeTab.Links) > 0 {
+				for _, link := range activeTab.Links {
 					if strings.Contains(strings.ToLower(link.Text), "title") ||
 						strings.Contains(strings.ToLower(link.Text), "heading") {
 						title = link.Text
@@ -538,16 +1255,16 @@ func (m *model) handleBookmark() (tea.Model, tea.Cmd) {
 				}
 			}
 			m.addBookmark(title)
-			m.content = fmt.Sprintf("⭐ Bookmarked: %s", title)
-			m.setError("")
+			activeTab.Display = fmt.Sprintf("⭐ Bookmarked: %s", title)
+			activeTab.setError("")
 			if m.ready {
-				m.viewport.SetContent(m.content)
+				m.viewport.SetContent(activeTab.Display)
 			}
 		} else {
-			m.content = "✅ Already bookmarked!"
-			m.setError("")
+			activeTab.Display = "✅ Already bookmarked!"
+			activeTab.setError("")
 			if m.ready {
-				m.viewport.SetContent(m.content)
+				m.viewport.SetContent(activeTab.Display)
 			}
 		}
 	}
@@ -564,11 +1281,9 @@ func (m *model) handleGoBack() (tea.Model, tea.Cmd) {
 	activeTab := m.activeTabPtr()
 	if activeTab != nil && activeTab.canGoBack() {
 		activeTab.goBack()
-		m.updateLoading("Going back...")
-		m.content = "🔄 Going back..."
-		m.readerMode = false
-		activeTab.ReaderMode = false
-		return m, fetchContentWithLinks(activeTab.URL, m.activeTab)
+		activeTab.updateLoading("Going back...")
+		activeTab.Display = "🔄 Going back..."
+		return m, m.fetchEntry(activeTab)
 	}
 	return m, nil
 }
@@ -577,137 +1292,255 @@ func (m *model) handleGoForward() (tea.Model, tea.Cmd) {
 	activeTab := m.activeTabPtr()
 	if activeTab != nil && activeTab.canGoForward() {
 		activeTab.goForward()
-		m.updateLoading("Going forward...")
-		m.content = "🔄 Going forward..."
-		m.readerMode = false
-		activeTab.ReaderMode = false
-		return m, fetchContentWithLinks(activeTab.URL, m.activeTab)
+		activeTab.updateLoading("Going forward...")
+		activeTab.Display = "🔄 Going forward..."
+		return m, m.fetchEntry(activeTab)
 	}
 	return m, nil
 }
 
 func (m *model) handleEscape() (tea.Model, tea.Cmd) {
-	if m.showHistory || m.showBookmarks || m.showSearch || m.showImages {
-		m.showHistory = false
-		m.showBookmarks = false
-		m.showSearch = false
-		m.showImages = false
-		m.readerMode = false
-		m.currentImage = nil
-		activeTab := m.activeTabPtr()
-		if activeTab != nil && len(activeTab.History) > 0 && activeTab.CurrentPos >= 0 {
-			m.updateLoading("Loading current page...")
-			m.content = "🔄 Loading current page..."
-			return m, fetchContentWithLinks(activeTab.History[activeTab.CurrentPos], m.activeTab)
+	activeTab := m.activeTabPtr()
+	if activeTab == nil {
+		return m, nil
+	}
+	if len(m.suggestions) > 0 {
+		m.clearSuggestions()
+		return m, nil
+	}
+	if activeTab.ShowTOC {
+		m.scrollToLine(activeTab, activeTab.ScrollOffset)
+		return m, nil
+	}
+	if m.canReturnToSearch(activeTab) {
+		m.urlInput.SetValue("")
+		m.showSearchPage(activeTab, activeTab.SearchPage)
+		return m, nil
+	}
+	if activeTab.overlayShown() {
+		activeTab.clearOverlays()
+		activeTab.ReaderMode = false
+		activeTab.CurrentImage = nil
+		if len(activeTab.History) > 0 && activeTab.CurrentPos >= 0 {
+			activeTab.updateLoading("Loading current page...")
+			activeTab.Display = "🔄 Loading current page..."
+			return m, m.fetchEntry(activeTab)
 		} else {
-			m.content = "🌐 Enter a URL or search query to start browsing"
-			m.setError("")
+			activeTab.Display = "🌐 Enter a URL or search query to start browsing"
+			activeTab.setError("")
 			if m.ready {
-				m.viewport.SetContent(m.content)
+				m.viewport.SetContent(activeTab.Display)
 			}
 		}
-	} else if m.readerMode {
-		m.readerMode = false
-		activeTab := m.activeTabPtr()
-		if activeTab != nil {
-			activeTab.ReaderMode = false
-		}
-		m.updateLoading("Loading original view...")
-		m.content = "🔄 Loading original view..."
-		currentURL := activeTab.History[activeTab.CurrentPos]
+	} else if activeTab.ReaderMode {
+		activeTab.ReaderMode = false
+		activeTab.updateLoading("Loading original view...")
+		activeTab.Display = "🔄 Loading original view..."
+		currentURL := activeTab.History[activeTab.CurrentPos].URL
 		m.urlInput.SetValue("")
-		return m, fetchContentWithLinks(currentURL, m.activeTab)
+		return m, fetchContentWithLinks(currentURL, activeTab.ID)
+	}
+	return m, nil
+}
+
+// jumpToFragment records a same-page #fragment navigation and scrolls to it
+func (m *model) jumpToFragment(tab *Tab, url, fragment string) {
+	line, ok := findAnchor(tab.Anchors, fragment)
+	if !ok {
+		tab.setError(fmt.Sprintf("Anchor #%s not found on this page", fragment))
+		return
+	}
+	tab.navigateTo(url)
+	if entry := tab.currentEntry(); entry != nil {
+		entry.ReaderMode = tab.ReaderMode
+		entry.Title = tab.Title
+	}
+	m.scrollToLine(tab, line)
+	tab.setError("")
+}
+
+// scrollToLine shows the tab's page content scrolled to line
+func (m *model) scrollToLine(tab *Tab, line int) {
+	tab.ShowTOC = false
+	tab.Display = tab.Content
+	if m.ready {
+		m.viewport.SetContent(tab.Display)
+		m.viewport.SetYOffset(line)
+	}
+	m.syncViewState()
+}
+
+// handleHeadingJump moves to the next (dir > 0) or previous heading
+func (m *model) handleHeadingJump(dir int) (tea.Model, tea.Cmd) {
+	activeTab := m.activeTabPtr()
+	if activeTab == nil || !m.ready || activeTab.ShowTOC {
+		return m, nil
+	}
+	current := m.viewport.YOffset
+	toc := headings(activeTab.Anchors)
+	if dir > 0 {
+		for _, heading := range toc {
+			if heading.Line > current {
+				m.scrollToLine(activeTab, heading.Line)
+				return m, nil
+			}
+		}
+	} else {
+		for i := len(toc) - 1; i >= 0; i-- {
+			if toc[i].Line < current {
+				m.scrollToLine(activeTab, toc[i].Line)
+				return m, nil
+			}
+		}
 	}
 	return m, nil
 }
 
 func (m *model) handleOpenImage() (tea.Model, tea.Cmd) {
-	if m.currentImage != nil {
-		currentImg := m.currentImage
-		m.currentImage = nil // Reset so we don't get stuck in image view
-		m.content = fmt.Sprintf("📤 Opening image in external viewer: %s", currentImg.URL)
+	activeTab := m.activeTabPtr()
+	if activeTab.CurrentImage != nil {
+		currentImg := activeTab.CurrentImage
+		activeTab.CurrentImage = nil // Reset so we don't get stuck in image view
+		activeTab.Display = fmt.Sprintf("📤 Opening image in external viewer: %s", currentImg.URL)
 		if m.ready {
-			m.viewport.SetContent(m.content)
+			m.viewport.SetContent(activeTab.Display)
 		}
-		return m, m.openImageExternally(currentImg.URL)
+		return m, m.openImageExternally(currentImg.URL, activeTab.ID)
 	}
 	return m, nil
 }
 
 // Message handlers
 func (m *model) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
-	headerHeight := 4
-	footerHeight := 2
+	m.width = msg.Width
+	m.height = msg.Height
 
 	if !m.ready {
 		m.viewport = viewport.New(msg.Width, msg.Height-headerHeight-footerHeight)
-		m.viewport.YPosition = headerHeight
-		m.viewport.SetContent(m.content)
+		m.viewport.SetContent(m.activeTabPtr().Display)
 		m.ready = true
-	} else {
-		m.viewport.Width = msg.Width
-		m.viewport.Height = msg.Height - headerHeight - footerHeight
 	}
+	m.layoutPanes()
 
 	m.urlInput.Width = msg.Width - 2
 	return m, nil
 }
 
 func (m *model) handleFetchContent(msg fetchContentMsg) (tea.Model, tea.Cmd) {
-	m.loading = false
-	m.content = msg.content
-	m.links = msg.links
-	m.images = msg.images
+	// The tab may have been closed while it was loading, or gone on to
+	// another page before this one arrived
+	tab := m.tabByID(msg.tabID)
+	if tab == nil || newHistoryItem(msg.url).URL != tab.URL {
+		return m, nil
+	}
 
-	m.completeLoading(msg.loadTime, msg.pageSize, msg.statusCode, len(msg.links))
+	tab.Content = msg.content
+	tab.Markdown = stripAnchorMarkers(msg.markdown)
+	tab.marked = msg.markdown
+	tab.Display = msg.content
+	tab.Links = msg.links
+	tab.Images = msg.images
+	tab.Anchors = msg.anchors
+	tab.completeLoading(msg.loadTime, msg.pageSize, msg.statusCode, len(msg.links))
+	if len(msg.rewrites) > 0 {
+		tab.Status.RewrittenURL = msg.rewrittenURL
+		tab.Status.Rewrites = msg.rewrites
+	}
+	tab.Status.Blocked = msg.blocked
+	tab.Feeds = msg.feeds
+	if msg.title != "" {
+		tab.Title = msg.title
+	}
+	if entry := tab.currentEntry(); entry != nil {
+		entry.ReaderMode = msg.readerMode
+		if msg.title != "" {
+			entry.Title = msg.title
+		}
+	}
+	if m.config.EnableHistory && !tab.Private {
+		referrer := ""
+		if tab.CurrentPos > 0 && tab.CurrentPos < len(tab.History) {
+			referrer = tab.History[tab.CurrentPos-1].URL
+		}
+		m.history.Record(tab.URL, msg.title, referrer)
+	}
+	if m.config.EnableBookmarks && !tab.Private {
+		m.touchBookmark(tab.URL)
+	}
+	if m.shouldIndex(tab) {
+		m.pageIndex.Add(tab.URL, msg.title, tab.Markdown)
+	}
 
-	if msg.tabID >= 0 && msg.tabID < len(m.tabs) {
-		m.tabs[msg.tabID].Content = msg.content
-		m.tabs[msg.tabID].Links = msg.links
-		m.tabs[msg.tabID].Images = msg.images
+	tab.clearOverlays()
+
+	// Work out where the page should open: a saved position, a #fragment or the top
+	offset := 0
+	entry := tab.currentEntry()
+	if tab.restoreScroll {
+		offset = tab.ScrollOffset
+		tab.restoreScroll = false
+	} else if entry != nil && entry.Fragment != "" {
+		if line, ok := findAnchor(tab.Anchors, entry.Fragment); ok {
+			offset = line
+		}
 	}
+	tab.ScrollOffset = offset
+	tab.DisplayOffset = offset
 
-	m.showHistory = false
-	m.showBookmarks = false
-	m.showSearch = false
-	m.showImages = false
-	if m.ready {
-		m.viewport.SetContent(m.content)
+	// Background tabs keep their content until they are switched to
+	if tab == m.activeTabPtr() && m.ready {
+		m.viewport.SetContent(tab.Display)
 		m.viewport.GotoTop()
+		m.viewport.SetYOffset(offset)
+	} else if vp := m.unfocusedPaneFor(tab); vp != nil {
+		vp.SetContent(tab.Display)
+		vp.GotoTop()
+		vp.SetYOffset(offset)
 	}
 
 	return m, nil
 }
 
 func (m *model) handleSearchResults(msg searchResultsMsg) (tea.Model, tea.Cmd) {
-	m.loading = false
-	m.searchResults = msg.results
-	m.showSearch = true
-	m.content = m.renderSearchResults(msg.query, msg.results)
-	m.setError("")
-	if m.ready {
-		m.viewport.SetContent(m.content)
-		m.viewport.GotoTop()
+	tab := m.tabByID(msg.tabID)
+	if tab == nil || msg.page > len(tab.SearchPages) {
+		return m, nil
+	}
+	if msg.page > 0 && len(msg.results) == 0 {
+		// The engine offered another page but it came back empty
+		tab.SearchPages[msg.page-1].Next = nil
+		tab.setError("No more results")
+		if tab.ShowSearch {
+			m.showSearchPage(tab, msg.page-1)
+		}
+		return m, nil
 	}
+	tab.SearchPages = append(tab.SearchPages[:msg.page], searchPage{Results: msg.results, Next: msg.next})
+	tab.SearchQuery = msg.query
+	tab.SearchEngine = msg.engine
+	tab.setError("")
+	m.showSearchPage(tab, msg.page)
 	return m, nil
 }
 
 func (m *model) handleError(msg errorMsg) (tea.Model, tea.Cmd) {
-	m.loading = false
-	m.content = fmt.Sprintf("❌ Error: %v\n\nPress Enter to try another URL or search", msg.err)
-	m.setError(msg.err.Error())
-
-	if msg.tabID >= 0 && msg.tabID < len(m.tabs) {
-		m.tabs[msg.tabID].Content = m.content
+	tab := m.tabByID(msg.tabID)
+	if tab == nil {
+		return m, nil
 	}
-
-	m.showHistory = false
-	m.showBookmarks = false
-	m.showSearch = false
-	m.showImages = false
-	m.readerMode = false
-	if m.ready {
-		m.viewport.SetContent(m.content)
+	tab.Display = fmt.Sprintf("❌ Error: %v\n\nPress Enter to try another URL or search", msg.err)
+	tab.Content = tab.Display
+	tab.DisplayOffset = 0
+	tab.setError(msg.err.Error())
+
+	tab.clearOverlays()
+	tab.ReaderMode = false
+	if tab == m.activeTabPtr() && m.ready {
+		m.viewport.SetContent(tab.Display)
+		m.viewport.GotoTop()
+	} else if vp := m.unfocusedPaneFor(tab); vp != nil {
+		vp.SetContent(tab.Display)
+		vp.GotoTop()
 	}
 
 	return m, nil
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The file is compacted once it holds this many lines and more than twice
+// as many as there are URLs, so revisits don't grow it without bound
+const historyCompactLines = 1000
+
+// HistoryEntry is one URL in the global browsing history
+type HistoryEntry struct {
+	URL        string    `json:"url"`
+	Title      string    `json:"title,omitempty"`
+	VisitTime  time.Time `json:"visit_time"`
+	VisitCount int       `json:"visit_count"`
+	Referrer   string    `json:"referrer,omitempty"`
+}
+
+// historyRecord is a single line of the append-only history file.
+// Visits defaults to 1; compaction writes one aggregated record per URL.
+type historyRecord struct {
+	URL      string    `json:"url"`
+	Title    string    `json:"title,omitempty"`
+	Time     time.Time `json:"time"`
+	Referrer string    `json:"referrer,omitempty"`
+	Visits   int       `json:"visits,omitempty"`
+	Deleted  bool      `json:"deleted,omitempty"`
+}
+
+// HistoryStore keeps every visited URL in memory and appends visits to disk
+type HistoryStore struct {
+	file      string
+	entries   map[string]*HistoryEntry
+	retention time.Duration
+	exclude   []string
+	version   int // bumped on every change so completions can be cached
+	lines     int // records in the file, live or not
+}
+
+func loadHistory(filename string, retentionDays int, exclude []string) *HistoryStore {
+	h := &HistoryStore{
+		file:    filename,
+		entries: make(map[string]*HistoryEntry),
+		exclude: exclude,
+	}
+	if retentionDays > 0 {
+		h.retention = time.Duration(retentionDays) * 24 * time.Hour
+	}
+
+	needsCompaction := h.read()
+	if h.prune() {
+		needsCompaction = true
+	}
+	if needsCompaction || h.bloated() {
+		h.compact()
+	}
+	return h
+}
+
+// bloated reports whether most of the file is superseded visits
+func (h *HistoryStore) bloated() bool {
+	return h.lines > historyCompactLines && h.lines > 2*len(h.entries)
+}
+
+// read replays the history file into entries. Returns true if the file
+// holds tombstones or broken lines worth compacting away.
+func (h *HistoryStore) read() bool {
+	f, err := os.Open(h.file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	needsCompaction := false
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		h.lines++
+		var rec historyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			needsCompaction = true
+			continue
+		}
+		if rec.Deleted {
+			delete(h.entries, rec.URL)
+			needsCompaction = true
+			continue
+		}
+		h.apply(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading history: %v", err)
+	}
+	return needsCompaction
+}
+
+// apply folds a visit record into the in-memory entries
+func (h *HistoryStore) apply(rec historyRecord) {
+	visits := rec.Visits
+	if visits == 0 {
+		visits = 1
+	}
+	h.version++
+	entry, ok := h.entries[rec.URL]
+	if !ok {
+		entry = &HistoryEntry{URL: rec.URL}
+		h.entries[rec.URL] = entry
+	}
+	entry.VisitCount += visits
+	if rec.Time.After(entry.VisitTime) || entry.VisitTime.IsZero() {
+		entry.VisitTime = rec.Time
+		if rec.Referrer != "" {
+			entry.Referrer = rec.Referrer
+		}
+	}
+	if rec.Title != "" {
+		entry.Title = rec.Title
+	}
+}
+
+// prune drops entries older than the retention window. Returns true if any were removed.
+func (h *HistoryStore) prune() bool {
+	if h.retention == 0 {
+		return false
+	}
+	cutoff := h.cutoff()
+	pruned := false
+	for u, entry := range h.entries {
+		if entry.VisitTime.Before(cutoff) {
+			delete(h.entries, u)
+			h.version++
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// compact rewrites the file with one aggregated record per URL. The file is
+// re-read under the lock first so visits other instances appended survive.
+func (h *HistoryStore) compact() {
+	unlock := lockFile(h.file)
+	defer unlock()
+	h.entries = make(map[string]*HistoryEntry)
+	h.lines = 0
+	h.read()
+	h.prune()
+	h.write()
+}
+
+// write replaces the file with the current entries; the caller holds the lock
+func (h *HistoryStore) write() {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range h.Entries() {
+		enc.Encode(historyRecord{
+			URL:      entry.URL,
+			Title:    entry.Title,
+			Time:     entry.VisitTime,
+			Referrer: entry.Referrer,
+			Visits:   entry.VisitCount,
+		})
+	}
+	if err := writeFileAtomic(h.file, buf.Bytes()); err != nil {
+		log.Printf("Error compacting history: %v", err)
+		return
+	}
+	h.lines = len(h.entries)
+}
+
+func (h *HistoryStore) append(rec historyRecord) {
+	unlock := lockFile(h.file)
+	defer unlock()
+	f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Error writing history file: %v", err)
+		return
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(rec); err != nil {
+		log.Printf("Error writing history file: %v", err)
+		return
+	}
+	h.lines++
+}
+
+// cutoff is when the retention window starts, or the zero time
+func (h *HistoryStore) cutoff() time.Time {
+	if h.retention == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-h.retention)
+}
+
+// keeps reports whether a visit to url at t belongs in history, for data
+// kept alongside it like the page index
+func (h *HistoryStore) keeps(url string, t time.Time) bool {
+	return !h.isExcluded(url) && !t.Before(h.cutoff())
+}
+
+// isExcluded reports whether a URL matches the exclusion list.
+// Patterns with a * are matched against the host, others as substrings.
+func (h *HistoryStore) isExcluded(rawURL string) bool {
+	host := ""
+	if parsed, err := url.Parse(rawURL); err == nil {
+		host = parsed.Hostname()
+	}
+	for _, pattern := range h.exclude {
+		if strings.Contains(pattern, "*") {
+			if ok, _ := filepath.Match(pattern, host); ok {
+				return true
+			}
+		} else if strings.Contains(rawURL, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Record a visit to url
+func (h *HistoryStore) Record(rawURL, title, referrer string) {
+	rawURL = cleanLink(rawURL)
+	if rawURL == "" || strings.HasPrefix(rawURL, "help://") || strings.HasPrefix(rawURL, laterScheme) ||
+		h.isExcluded(rawURL) {
+		return
+	}
+	rec := historyRecord{
+		URL:      rawURL,
+		Title:    title,
+		Time:     time.Now(),
+		Referrer: cleanLink(referrer),
+	}
+	h.apply(rec)
+	h.append(rec)
+	if h.bloated() {
+		h.compact()
+	}
+}
+
+// Delete removes a URL from history
+func (h *HistoryStore) Delete(rawURL string) {
+	if _, ok := h.entries[rawURL]; !ok {
+		return
+	}
+	delete(h.entries, rawURL)
+	h.version++
+	h.append(historyRecord{URL: rawURL, Time: time.Now(), Deleted: true})
+	if h.bloated() {
+		h.compact()
+	}
+}
+
+// Clear removes all history
+func (h *HistoryStore) Clear() {
+	unlock := lockFile(h.file)
+	defer unlock()
+	h.entries = make(map[string]*HistoryEntry)
+	h.version++
+	h.write()
+}
+
+// Entries returns all history sorted by most recent visit first
+func (h *HistoryStore) Entries() []HistoryEntry {
+	entries := make([]HistoryEntry, 0, len(h.entries))
+	for _, entry := range h.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].VisitTime.After(entries[j].VisitTime)
+	})
+	return entries
+}
+
+// Search returns entries whose URL or title contains every query term
+func (h *HistoryStore) Search(query string) []HistoryEntry {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return h.Entries()
+	}
+	var results []HistoryEntry
+	for _, entry := range h.Entries() {
+		haystack := strings.ToLower(entry.URL + " " + entry.Title)
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(haystack, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			results = append(results, entry)
+		}
+	}
+	return results
+}
+
+// historyDayLabel groups visits under Today, Yesterday or a date heading
+func historyDayLabel(t time.Time) string {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case !t.Before(today):
+		return "Today"
+	case !t.Before(today.AddDate(0, 0, -1)):
+		return "Yesterday"
+	default:
+		return t.Format("Monday, January 2, 2006")
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Saved snapshots open from later://<id> URLs, so back, reload and session
+// restore work offline like any other page
+const laterScheme = "later://"
+
+// Version of the later.json layout
+const laterFormatVersion = 1
+
+// Where snapshots and their images are kept, set up at startup
+var laterDir = "later"
+
+// LaterItem is an article queued to read later. The snapshot itself is
+// kept in laterDir/<id>.json.
+type LaterItem struct {
+	ID       string    `json:"id"`
+	URL      string    `json:"url"`
+	Title    string    `json:"title"`
+	Saved    time.Time `json:"saved"`
+	Words    int       `json:"words"`
+	Images   int       `json:"images,omitempty"` // images downloaded with it
+	Read     bool      `json:"read,omitempty"`
+	Archived bool      `json:"archived,omitempty"`
+}
+
+// laterSnapshot is the reader-mode extraction of a page as saved
+type laterSnapshot struct {
+	URL      string      `json:"url"`
+	Title    string      `json:"title"`
+	Saved    time.Time   `json:"saved"`
+	Markdown string      `json:"markdown"` // with anchor markers, as extracted
+	Anchors  []Anchor    `json:"anchors"`
+	Links    []Link      `json:"links"`
+	Images   []ImageInfo `json:"images"` // local paths for downloaded images
+}
+
+// laterSavedMsg reports a finished save; item is only set on success
+type laterSavedMsg struct {
+	item  LaterItem
+	err   error
+	tabID int
+}
+
+// newLaterStore returns the store for the read-later list at filename
+func newLaterStore(filename string) *jsonStore[LaterItem] {
+	return &jsonStore[LaterItem]{file: filename, key: "items", version: laterFormatVersion, name: "read-later list"}
+}
+
+// updateLater applies change while holding the file lock, starting from
+// whatever another instance may have saved in the meantime
+func (m *model) updateLater(change func([]LaterItem) []LaterItem) {
+	m.laterStore.update(&m.later, change)
+}
+
+// laterIndex finds an item by ID, or -1
+func laterIndex(items []LaterItem, id string) int {
+	return slices.IndexFunc(items, func(item LaterItem) bool { return item.ID == id })
+}
+
+// newLaterID names a snapshot after when it was saved and its URL
+func newLaterID(pageURL string) string {
+	h := fnv.New32a()
+	h.Write([]byte(pageURL))
+	return fmt.Sprintf("%s-%08x", time.Now().Format("20060102-150405"), h.Sum32())
+}
+
+func laterSnapshotPath(id string) string {
+	return filepath.Join(laterDir, filepath.Base(id)+".json")
+}
+
+// readingTime estimates minutes to read words at an average pace
+func readingTime(words int) string {
+	return fmt.Sprintf("%d min read", max(1, (words+229)/230))
+}
+
+// saveForLater writes snapshot to the snapshot id, downloading its images
+// when asked. Without a snapshot the reader-mode extraction of pageURL is
+// fetched first.
+func saveForLater(pageURL, id string, snapshot *laterSnapshot, images bool, tabID int) tea.Cmd {
+	fetchURL, rewrites := rewriter.Rewrite(pageURL)
+	filter := contentFilter.forPage(fetchURL)
+	return func() tea.Msg {
+		if snapshot == nil {
+			doc, err := fetchHTML(fetchURL)
+			if err != nil {
+				return laterSavedMsg{err: rewrittenError(err, fetchURL, rewrites), tabID: tabID}
+			}
+			filter.Apply(doc, fetchURL)
+			page := extractPage(doc, fetchURL, true)
+			snapshot = &laterSnapshot{
+				URL:      pageURL,
+				Title:    page.Title,
+				Markdown: page.markdown,
+				Anchors:  page.anchors,
+				Links:    page.links,
+				Images:   page.images,
+			}
+		}
+		snapshot.Saved = time.Now()
+		if snapshot.Title == "" {
+			snapshot.Title = stripScheme(pageURL)
+		}
+		downloaded := 0
+		if images {
+			downloaded = downloadLaterImages(id, snapshot.Images)
+		}
+
+		data, err := json.Marshal(snapshot)
+		if err == nil {
+			err = writeFileAtomic(laterSnapshotPath(id), data)
+		}
+		if err != nil {
+			return laterSavedMsg{err: err, tabID: tabID}
+		}
+		return laterSavedMsg{
+			item: LaterItem{
+				ID:     id,
+				URL:    pageURL,
+				Title:  snapshot.Title,
+				Saved:  snapshot.Saved,
+				Words:  len(strings.Fields(stripAnchorMarkers(snapshot.Markdown))),
+				Images: downloaded,
+			},
+			tabID: tabID,
+		}
+	}
+}
+
+// Images bigger than this are left online
+const maxLaterImageSize = 20 << 20
+
+// downloadLaterImages stores images under laterDir/<id>/ and points them at
+// the local copies. Images that fail to download keep their URL.
+func downloadLaterImages(id string, images []ImageInfo) int {
+	dir := filepath.Join(laterDir, filepath.Base(id))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Error creating %s: %v", dir, err)
+		return 0
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	downloaded := 0
+	for i := range images {
+		file, err := downloadImage(client, images[i].URL, filepath.Join(dir, strconv.Itoa(images[i].Number)))
+		if err != nil {
+			log.Printf("Error saving image %s: %v", images[i].URL, err)
+			continue
+		}
+		images[i].URL = file
+		downloaded++
+	}
+	return downloaded
+}
+
+// downloadImage saves imageURL as base plus an extension and returns the file
+func downloadImage(client *http.Client, imageURL, base string) (string, error) {
+	req, err := http.NewRequest("GET", imageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	ext := ""
+	if parsed, err := url.Parse(imageURL); err == nil {
+		ext = strings.ToLower(path.Ext(parsed.Path))
+	}
+	if len(ext) < 2 || len(ext) > 5 {
+		ext = ".img"
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLaterImageSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxLaterImageSize {
+		return "", fmt.Errorf("larger than %d MB", maxLaterImageSize>>20)
+	}
+	file := base + ext
+	return file, writeFileAtomic(file, data)
+}
+
+// loadLaterSnapshot opens a saved snapshot without touching the network
+func loadLaterSnapshot(id string, tabID int) tea.Cmd {
+	return func() tea.Msg {
+		start := time.Now()
+		data, err := os.ReadFile(laterSnapshotPath(id))
+		if err != nil {
+			return errorMsg{err: fmt.Errorf("saved copy missing: %v", err), tabID: tabID}
+		}
+		var snapshot laterSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return errorMsg{err: fmt.Errorf("saved copy unreadable: %v", err), tabID: tabID}
+		}
+
+		header := fmt.Sprintf("*📥 Saved %s from %s*\n\n", snapshot.Saved.Local().Format("Jan 2, 2006"), snapshot.URL)
+		markdown := header + snapshot.Markdown
+		styledContent, anchors, err := renderWithAnchors(markdown, snapshot.Anchors)
+		if err != nil {
+			return errorMsg{err: err, tabID: tabID}
+		}
+		return fetchContentMsg{
+			title:      snapshot.Title,
+			content:    styledContent,
+			markdown:   markdown,
+			anchors:    anchors,
+			links:      snapshot.Links,
+			images:     snapshot.Images,
+			tabID:      tabID,
+			url:        laterScheme + id,
+			loadTime:   time.Since(start),
+			pageSize:   len(markdown),
+			statusCode: 200,
+			readerMode: true,
+		}
+	}
+}
+
+// Handle "later" and "later images" to save the current page, and
+// "later list [archived]", "later read|unread|archive|delete <n>"
+func (m *model) handleLaterCommand(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	verb, rest, _ := strings.Cut(args, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch verb {
+	case "", "images":
+		return m.queueForLater(activeTab, verb == "images" || m.config.LaterImages)
+	case "list":
+		if rest != "" && rest != "archived" {
+			activeTab.setError("Usage: later list [archived]")
+			return nil
+		}
+		m.showLater(activeTab, rest == "archived", "")
+		activeTab.setError("")
+		return nil
+	case "read", "unread", "archive", "delete":
+		num, err := strconv.Atoi(rest)
+		if err != nil || num < 1 || num > len(activeTab.LaterResults) || !activeTab.ShowLater {
+			activeTab.setError(fmt.Sprintf("Usage: later %s <number from later list>", verb))
+			return nil
+		}
+		index := laterIndex(m.later, activeTab.LaterResults[num-1])
+		if index < 0 {
+			activeTab.setError("That item was removed")
+			return nil
+		}
+		item := m.later[index]
+		m.updateLater(func(items []LaterItem) []LaterItem {
+			i := laterIndex(items, item.ID)
+			if i < 0 {
+				return items
+			}
+			switch verb {
+			case "read", "unread":
+				items[i].Read = verb == "read"
+			case "archive":
+				items[i].Archived = !items[i].Archived
+			case "delete":
+				return slices.Delete(items, i, i+1)
+			}
+			return items
+		})
+		note := ""
+		switch {
+		case verb == "delete":
+			removeLaterSnapshot(item.ID)
+			note = "Deleted " + item.Title
+		case verb == "archive" && item.Archived:
+			note = "Moved back from the archive: " + item.Title
+		case verb == "archive":
+			note = "Archived " + item.Title
+		default:
+			note = fmt.Sprintf("Marked %s: %s", verb, item.Title)
+		}
+		m.showLater(activeTab, activeTab.LaterArchived, note)
+		activeTab.setError("")
+		return nil
+	default:
+		activeTab.setError("Usage: later [images|list|read|unread|archive|delete <n>]")
+		return nil
+	}
+}
+
+// queueForLater starts saving the page open in tab
+func (m *model) queueForLater(tab *Tab, images bool) tea.Cmd {
+	pageURL := tab.URL
+	if tab.CurrentPos < 0 || pageURL == "" || strings.HasPrefix(pageURL, "help://") {
+		tab.setError("Open a page to save it for later")
+		return nil
+	}
+	if strings.HasPrefix(pageURL, laterScheme) {
+		tab.setError("This is already a saved copy")
+		return nil
+	}
+	pageURL = cleanLink(pageURL)
+
+	// Saving a page again refreshes its snapshot
+	id := newLaterID(pageURL)
+	if i := slices.IndexFunc(m.later, func(item LaterItem) bool { return item.URL == pageURL }); i >= 0 {
+		id = m.later[i].ID
+	}
+	// A page already read in reader mode is saved as it is shown, so saving
+	// works offline and keeps what was read
+	var snapshot *laterSnapshot
+	if entry := tab.currentEntry(); entry != nil && entry.ReaderMode && !tab.Status.Loading && tab.marked != "" {
+		snapshot = &laterSnapshot{
+			URL:      pageURL,
+			Title:    tab.Title,
+			Markdown: tab.marked,
+			Anchors:  slices.Clone(tab.Anchors),
+			Links:    slices.Clone(tab.Links),
+			Images:   slices.Clone(tab.Images),
+		}
+	}
+	tab.setNotice("📥 Saving for later...")
+	return saveForLater(pageURL, id, snapshot, images, tab.ID)
+}
+
+func (m *model) handleLaterSaved(msg laterSavedMsg) (tea.Model, tea.Cmd) {
+	tab := m.tabByID(msg.tabID)
+	if msg.err != nil {
+		if tab != nil {
+			tab.setError(fmt.Sprintf("Couldn't save for later: %v", msg.err))
+		}
+		return m, nil
+	}
+	m.updateLater(func(items []LaterItem) []LaterItem {
+		if i := laterIndex(items, msg.item.ID); i >= 0 {
+			items[i] = msg.item
+			return items
+		}
+		return append(items, msg.item)
+	})
+	if tab != nil {
+		notice := fmt.Sprintf("📥 Saved for later: %s (%s)", msg.item.Title, readingTime(msg.item.Words))
+		if msg.item.Images > 0 {
+			notice += fmt.Sprintf(", %d images", msg.item.Images)
+		}
+		tab.setNotice(notice)
+	}
+	for i := range m.tabs {
+		if m.tabs[i].ShowLater {
+			m.showLater(&m.tabs[i], m.tabs[i].LaterArchived, "")
+		}
+	}
+	return m, nil
+}
+
+// removeLaterSnapshot deletes a snapshot and its images
+func removeLaterSnapshot(id string) {
+	for _, file := range []string{laterSnapshotPath(id), filepath.Join(laterDir, filepath.Base(id))} {
+		if err := os.RemoveAll(file); err != nil {
+			log.Printf("Error removing %s: %v", file, err)
+		}
+	}
+}
+
+// showLater puts the read-later view in tab: unread items first, then read
+// ones, newest first within each, or only the archive. The view keeps item
+// IDs, since another instance may change the list under it.
+func (m *model) showLater(tab *Tab, archived bool, note string) {
+	tab.LaterArchived = archived
+	tab.LaterResults = nil
+	for _, read := range []bool{false, true} {
+		for i := len(m.later) - 1; i >= 0; i-- {
+			item := m.later[i]
+			if item.Archived == archived && (archived || item.Read == read) {
+				tab.LaterResults = append(tab.LaterResults, item.ID)
+			}
+		}
+		if archived {
+			break
+		}
+	}
+	tab.clearOverlays()
+	tab.ShowLater = true
+	tab.ReaderMode = false
+	tab.Display = m.renderLater(tab, note)
+	if tab == m.activeTabPtr() && m.ready {
+		m.viewport.SetContent(tab.Display)
+	}
+}
+
+// openLaterItem opens the saved copy of item n of the read-later view
+func (m *model) openLaterItem(num int, tab *Tab) tea.Cmd {
+	index := laterIndex(m.later, tab.LaterResults[num-1])
+	if index < 0 {
+		m.urlInput.SetValue("")
+		tab.setError("That item was removed")
+		return nil
+	}
+	item := m.later[index]
+	pageURL := laterScheme + item.ID
+	tab.updateLoading("Opening saved copy...")
+	tab.Display = fmt.Sprintf("🔄 Opening: %s", item.Title)
+	tab.navigateTo(pageURL)
+	tab.ShowLater = false
+	tab.ReaderMode = true
+	tab.CurrentImage = nil
+	m.urlInput.SetValue("")
+	return loadLaterSnapshot(item.ID, tab.ID)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// RedirectWrapper is a click-tracking URL that carries the real target in
+// a query parameter, like https://www.google.com/url?q=<target>
+type RedirectWrapper struct {
+	Host  string `json:"host"` // "*.example.com" also matches subdomains
+	Path  string `json:"path"`
+	Param string `json:"param"`
+}
+
+// Query parameters that only identify the click. A trailing * matches any
+// parameter starting with the rest.
+var builtinTrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "twclid",
+	"igshid", "mc_cid", "mc_eid", "_ga", "_gl", "_hsenc", "_hsmi", "__hssc", "__hstc", "__hsfp",
+	"hsctatracking", "mkt_tok", "oly_anon_id", "oly_enc_id", "vero_id", "vero_conv", "wickedid",
+	"ref_src", "ref_url", "s_cid", "trk", "trkcampaign", "sc_campaign", "sc_channel", "sc_content",
+	"sc_medium", "sc_outcome", "sc_geo", "sc_country", "pk_campaign", "pk_kwd", "pk_source",
+	"pk_medium", "mtm_*", "ncid", "cmpid", "spm", "scm",
+}
+
+var builtinRedirectWrappers = []RedirectWrapper{
+	{Host: "*.duckduckgo.com", Path: "/l/", Param: "uddg"},
+	{Host: "*.google.com", Path: "/url", Param: "q"},
+	{Host: "*.google.com", Path: "/url", Param: "url"},
+	{Host: "l.facebook.com", Path: "/l.php", Param: "u"},
+	{Host: "lm.facebook.com", Path: "/l.php", Param: "u"},
+	{Host: "l.instagram.com", Path: "/", Param: "u"},
+	{Host: "*.youtube.com", Path: "/redirect", Param: "q"},
+	{Host: "out.reddit.com", Path: "", Param: "url"},
+	{Host: "steamcommunity.com", Path: "/linkfilter/", Param: "url"},
+	{Host: "*.linkedin.com", Path: "/redir/redirect", Param: "url"},
+}
+
+// LinkCleaner unwraps redirect wrappers and strips tracking parameters
+// from links before they are shown, saved or copied
+type LinkCleaner struct {
+	Enabled  bool
+	Params   []string
+	Wrappers []RedirectWrapper
+}
+
+// The cleaner used for every link, set up from the config at startup
+var linkCleaner = newLinkCleaner(true, nil, nil)
+
+// newLinkCleaner adds the configured parameters and wrappers to the built-in ones
+func newLinkCleaner(enabled bool, params []string, wrappers []RedirectWrapper) *LinkCleaner {
+	c := &LinkCleaner{
+		Enabled:  enabled,
+		Params:   append([]string(nil), builtinTrackingParams...),
+		Wrappers: append([]RedirectWrapper(nil), builtinRedirectWrappers...),
+	}
+	for _, param := range params {
+		c.Params = append(c.Params, strings.ToLower(param))
+	}
+	c.Wrappers = append(c.Wrappers, wrappers...)
+	return c
+}
+
+// cleanLink is linkCleaner.Clean
+func cleanLink(link string) string {
+	return linkCleaner.Clean(link)
+}
+
+// Clean returns link with redirect wrappers removed and tracking
+// parameters dropped, leaving the rest of the query as it was
+func (c *LinkCleaner) Clean(link string) string {
+	if !c.Enabled {
+		return link
+	}
+	link = c.Unwrap(link)
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.RawQuery == "" {
+		return link
+	}
+	var kept []string
+	for _, pair := range strings.Split(parsed.RawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if pair != "" && !c.isTracking(key) {
+			kept = append(kept, pair)
+		}
+	}
+	parsed.RawQuery = strings.Join(kept, "&")
+	return parsed.String()
+}
+
+func (c *LinkCleaner) isTracking(param string) bool {
+	param = strings.ToLower(param)
+	for _, tracking := range c.Params {
+		if prefix, ok := strings.CutSuffix(tracking, "*"); ok {
+			if strings.HasPrefix(param, prefix) {
+				return true
+			}
+		} else if param == tracking {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap follows redirect wrappers, including wrappers inside wrappers, to
+// the link they point at. It works even with cleaning turned off, since
+// search results depend on it.
+func (c *LinkCleaner) Unwrap(link string) string {
+	for range 5 {
+		target := c.unwrapOnce(link)
+		if target == link {
+			break
+		}
+		link = target
+	}
+	return link
+}
+
+func (c *LinkCleaner) unwrapOnce(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.RawQuery == "" {
+		return link
+	}
+	for _, wrapper := range c.Wrappers {
+		if !matchHost(link, []string{wrapper.Host}) || (wrapper.Path != "" && parsed.Path != wrapper.Path) {
+			continue
+		}
+		target := parsed.Query().Get(wrapper.Param)
+		if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+			return target
+		}
+	}
+	return link
+}
+
+// unwrapRedirect returns the target of a click-tracking link
+func unwrapRedirect(link string) string {
+	return linkCleaner.Unwrap(link)
+}
+
+// Handle "copy" and "copy <n>": put the page URL or link n on the clipboard
+func (m *model) handleCopy(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	link := activeTab.URL
+	if args != "" {
+		num, err := strconv.Atoi(args)
+		if err != nil || num < 1 || num > len(activeTab.Links) {
+			activeTab.setError("Usage: copy [link number]")
+			return nil
+		}
+		link = activeTab.Links[num-1].FullURL
+	}
+	if link == "" {
+		activeTab.setError("Nothing to copy")
+		return nil
+	}
+	link = cleanLink(link)
+	if err := clipboard.WriteAll(link); err != nil {
+		activeTab.setError(fmt.Sprintf("Couldn't copy: %v", err))
+		return nil
+	}
+	activeTab.setNotice("📋 Copied " + link)
+	return nil
+}
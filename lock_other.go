@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// Without flock, instances rely on atomic renames and change detection
+func tryFlock(f *os.File) (bool, error) { return true, nil }
+
+func funlock(f *os.File) error { return nil }
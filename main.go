@@ -1,6 +1,7 @@
 package main
 
 import (
+	_ "embed"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,10 +9,12 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -30,6 +33,43 @@ type Config struct {
 	PageCacheSize      int  `json:"page_cache_size"`
 	EnableMouseSupport bool `json:"enable_mouse_support"`
 	StatusPanelTimeout int  `json:"status_panel_timeout"` // seconds
+
+	HistoryRetentionDays int      `json:"history_retention_days"` // 0 keeps everything
+	HistoryExclude       []string `json:"history_exclude"`
+	EnablePageIndex      bool     `json:"enable_page_index"` // full-text index of visited pages
+
+	RestoreSession      bool   `json:"restore_session"`
+	Session             string `json:"session"`               // named session to open at startup
+	SessionSaveInterval int    `json:"session_save_interval"` // seconds, 0 disables autosave
+
+	// Override the XDG data and cache directories
+	DataDir  string `json:"data_dir"`
+	CacheDir string `json:"cache_dir"`
+
+	SearchEngines []SearchEngine `json:"search_engines"` // added to the built-in engines
+	DefaultEngine string         `json:"default_engine"` // keyword or name
+
+	EnableRemote bool `json:"enable_remote"` // listen for remote control commands
+
+	Extractors []SelectorExtractor `json:"extractors"` // tried before the built-in site extractors
+
+	EnableRewrites bool          `json:"enable_rewrites"`
+	RewriteRules   []RewriteRule `json:"rewrite_rules"` // added to or overriding the built-in rules
+
+	FilterLists     []string `json:"filter_lists"`     // Adblock Plus / EasyList files
+	FilterWhitelist []string `json:"filter_whitelist"` // sites left unfiltered
+
+	FeedRefreshInterval int `json:"feed_refresh_interval"` // minutes, 0 only refreshes on request
+
+	LaterImages bool `json:"later_images"` // download images with every read-later snapshot
+
+	CleanLinks       bool              `json:"clean_links"`       // strip tracking parameters and redirect wrappers
+	TrackingParams   []string          `json:"tracking_params"`   // added to the built-in list, "prefix_*" allowed
+	RedirectWrappers []RedirectWrapper `json:"redirect_wrappers"` // added to the built-in wrappers
+
+	// Set from the command line only
+	Dump       DumpOptions `json:"-"`
+	RemoteArgs []string    `json:"-"` // command for a running instance, from -remote
 }
 
 func DefaultConfig() Config {
@@ -44,6 +84,21 @@ func DefaultConfig() Config {
 		PageCacheSize:      50,
 		EnableMouseSupport: true,
 		StatusPanelTimeout: 5,
+
+		HistoryRetentionDays: 90,
+		HistoryExclude:       []string{},
+		EnablePageIndex:      true,
+
+		RestoreSession:      true,
+		SessionSaveInterval: 30,
+
+		DefaultEngine: "ddg",
+		EnableRemote:  true,
+
+		EnableRewrites: true,
+		CleanLinks:     true,
+
+		FeedRefreshInterval: 60,
 	}
 }
 
@@ -58,8 +113,13 @@ type Link struct {
 
 // Bookmark represents a saved website
 type Bookmark struct {
-	Title string `json:"title"`
-	URL   string `json:"url"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Tags        []string  `json:"tags,omitempty"`
+	Folder      string    `json:"folder,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Created     time.Time `json:"created"`
+	LastVisited time.Time `json:"last_visited"`
 }
 
 // SearchResult represents a search result
@@ -76,11 +136,58 @@ type Tab struct {
 	Title      string
 	URL        string
 	Content    string
+	Markdown   string // the page before styling
+	marked     string // Markdown still carrying the anchor markers
 	Links      []Link
 	Images     []ImageInfo
+	Anchors    []Anchor
 	ReaderMode bool
-	History    []string
+	History    []HistoryItem
 	CurrentPos int
+	Pinned     bool       // pinned tabs sit at the left and refuse Ctrl+W
+	Private    bool       // private tabs leave no history, index or session behind
+	Feeds      []PageFeed // feeds the page advertises
+
+	ScrollOffset  int
+	restoreScroll bool // apply ScrollOffset on the next load instead of jumping to top
+
+	// View state, kept per tab so background loads and overlays don't leak
+	// into whichever tab happens to be visible
+	Display       string // what the viewport shows: the page or an overlay
+	DisplayOffset int
+	ShowImages    bool
+	ShowHistory   bool
+	ShowBookmarks bool
+	ShowSearch    bool
+	ShowTOC       bool
+	ShowClosed    bool
+	ShowTabs      bool
+	ShowFind      bool
+	ShowFeeds     bool
+	ShowLater     bool
+	SearchResults []SearchResult
+	SearchQuery   string
+	CurrentImage  *ImageInfo
+	Status        StatusInfo
+
+	// Results of the other list views, numbered as shown
+	HistoryResults  []HistoryEntry
+	HistoryQuery    string
+	BookmarkResults []string // URLs
+	BookmarkQuery   string
+	FindResults     []pageHit
+	FindQuery       string
+	TabResults      []int // tab IDs
+	FeedResults     []feedRef
+	LaterResults    []string // item IDs
+	LaterArchived   bool     // the later view shows the archive
+
+	// Search state survives opening a result so Escape can return to it
+	SearchEngine   SearchEngine
+	SearchPages    []searchPage // every page fetched for SearchQuery so far
+	SearchPage     int          // index of the page being shown
+	SearchSelected int          // highlighted result, -1 for none
+	searchReturn   string       // URL of the result opened from the search view
 }
 
 // NEW: Status information for bottom panel
@@ -93,6 +200,10 @@ type StatusInfo struct {
 	LinkCount    int
 	StatusCode   int
 	Error        string
+	RewrittenURL string   // where the page was actually fetched from
+	Rewrites     []string // rules that changed the URL
+	Blocked      int      // elements and images removed by filter lists
+	Notice       string   // one-off message, such as a copied link
 }
 
 // ImageInfo represents an image on the page
@@ -108,35 +219,67 @@ type ImageInfo struct {
 type model struct {
 	viewport      viewport.Model
 	urlInput      textinput.Model
-	content       string
+	spinner       spinner.Model
+	spinning      bool
 	ready         bool
-	loading       bool
 	tabs          []Tab
 	activeTab     int
-	links         []Link
-	images        []ImageInfo
-	showImages    bool
-	showHistory   bool
+	nextTabID     int
 	bookmarks     []Bookmark
-	showBookmarks bool
-	bookmarkFile  string
-	searchResults []SearchResult
-	showSearch    bool
-	searchQuery   string
-	readerMode    bool
-	status        StatusInfo
+	bookmarkStore *jsonStore[Bookmark]
+	paths         Paths
+	closedTabs    []closedTab
 	config        Config
-	currentImage  *ImageInfo
+
+	// URL bar autocompletion
+	suggestions     []Suggestion
+	suggestionIndex int
+
+	// History completions, rebuilt when history.version moves on
+	historyCandidates        []Suggestion
+	historyCandidatesVersion int
+
+	// Global browsing history
+	history *HistoryStore
+
+	// Full-text index of visited pages
+	pageIndex *PageIndex
+
+	// Split panes; m.viewport always belongs to the focused pane
+	split       splitMode
+	panes       [2]pane
+	focusedPane int
+	splitRatio  float64
+	width       int
+	height      int
+
+	// Feed subscriptions
+	feeds           []Feed
+	feedStore       *jsonStore[Feed]
+	feedsRefreshing bool
+
+	// Read-later list
+	later      []LaterItem
+	laterStore *jsonStore[LaterItem]
 }
 
 type fetchContentMsg struct {
-	content    string
-	links      []Link
-	images     []ImageInfo
-	tabID      int
-	loadTime   time.Duration
-	pageSize   int
-	statusCode int
+	title        string
+	content      string
+	markdown     string // the page before styling, for the page index
+	anchors      []Anchor
+	links        []Link
+	images       []ImageInfo
+	tabID        int
+	url          string // the URL asked for, to drop results the tab has moved on from
+	loadTime     time.Duration
+	pageSize     int
+	statusCode   int
+	readerMode   bool
+	rewrittenURL string   // the URL after rewrite rules
+	rewrites     []string // rules that changed it
+	blocked      int      // elements and images removed by filter lists
+	feeds        []PageFeed
 }
 
 type errorMsg struct {
@@ -146,7 +289,11 @@ type errorMsg struct {
 
 type searchResultsMsg struct {
 	query   string
+	engine  SearchEngine
+	page    int
 	results []SearchResult
+	next    *searchRequest
+	tabID   int
 }
 
 func LoadConfig(filename string) Config {
@@ -169,42 +316,37 @@ func InitialModel(config Config) model {
 	ti.Focus()
 	ti.CharLimit = 500
 	ti.Width = 50
-
-	bookmarkFile := "bookmarks.json"
-	bookmarks := loadBookmarks(bookmarkFile)
+	ti.ShowSuggestions = true
+
+	paths := resolvePaths(config)
+	migrateLegacyFiles(paths)
+
+	bookmarkStore := newBookmarkStore(paths.data("bookmarks.json"))
+	feedStore := newFeedStore(paths.data("feeds.json"))
+	laterStore := newLaterStore(paths.data("later.json"))
+	history := loadHistory(paths.data("history.jsonl"), config.HistoryRetentionDays, config.HistoryExclude)
+	// Indexed pages go when their history does
+	pageIndex := newPageIndex(filepath.Join(paths.Cache, "pages"))
+	pageIndex.keep = history.keeps
+	if cutoff := history.cutoff(); !cutoff.IsZero() {
+		pageIndex.Prune(cutoff)
+	}
 
 	// Load help content
-	helpContent := loadHelpContent()
+	helpContent := loadHelpContent(paths.Config)
 
 	initialTab := Tab{
 		ID:         0,
 		Title:      "Help",
 		URL:        "help://welcome",
 		Content:    helpContent,
+		Display:    helpContent,
 		Links:      []Link{},
 		Images:     []ImageInfo{},
 		ReaderMode: false,
-		History:    []string{},
+		History:    []HistoryItem{},
 		CurrentPos: -1,
-	}
-
-	return model{
-		urlInput:      ti,
-		content:       helpContent,
-		loading:       false,
-		tabs:          []Tab{initialTab},
-		activeTab:     0,
-		links:         []Link{},
-		images:        []ImageInfo{},
-		showHistory:   false,
-		bookmarks:     bookmarks,
-		showBookmarks: false,
-		bookmarkFile:  bookmarkFile,
-		searchResults: []SearchResult{},
-		showSearch:    false,
-		searchQuery:   "",
-		readerMode:    false,
-		status: StatusInfo{
+		Status: StatusInfo{
 			Loading:      false,
 			LoadingStage: "Ready",
 			LoadTime:     0,
@@ -212,44 +354,42 @@ func InitialModel(config Config) model {
 			LinkCount:    0,
 			StatusCode:   0,
 		},
-		config: config,
+	}
+
+	return model{
+		urlInput:        ti,
+		spinner:         spinner.New(spinner.WithSpinner(spinner.MiniDot)),
+		tabs:            []Tab{initialTab},
+		activeTab:       0,
+		nextTabID:       1,
+		bookmarks:       bookmarkStore.load(),
+		bookmarkStore:   bookmarkStore,
+		paths:           paths,
+		config:          config,
+		suggestionIndex: -1,
+		history:         history,
+		pageIndex:       pageIndex,
+		feeds:           feedStore.load(),
+		feedStore:       feedStore,
+		later:           laterStore.load(),
+		laterStore:      laterStore,
 	}
 }
 
-func loadHelpContent() string {
-	// Try to load from help.md file first
-	if data, err := os.ReadFile("help.md"); err == nil {
-		styled, err := renderWithStyle(string(data))
-		if err == nil {
-			return styled
-		}
-		return string(data) // Fallback to raw markdown
-	}
-
-	// Fallback embedded help
-	fallbackHelp := `# 🌐 Terminal Browser Help
-    
-## Quick Start
-- Type a **URL** to visit a website
-- Type any **text** to search the web  
-- Use **↑/↓** to scroll, **Enter** to submit
-- Press **1,2,3...** to follow links
-- Type **img1, img2...** to view images
-
-## Essential Shortcuts
-- **Ctrl+T** - New tab
-- **Ctrl+W** - Close tab  
-- **Ctrl+R** - Reload
-- **Ctrl+E** - Reader mode
-- **Ctrl+D** - Bookmark page
-- **history** - View history
-- **bookmarks** - View bookmarks
-
-*Type 'help' anytime to see this page*`
-
-	styled, err := renderWithStyle(fallbackHelp)
+// The built-in help page; a help.md in the config directory replaces it
+//
+//go:embed help.md
+var embeddedHelp string
+
+func loadHelpContent(configDir string) string {
+	help := embeddedHelp
+	if data, err := os.ReadFile(filepath.Join(configDir, "help.md")); err == nil {
+		help = string(data)
+	}
+
+	styled, err := renderWithStyle(help)
 	if err != nil {
-		return fallbackHelp
+		return help // Fallback to raw markdown
 	}
 	return styled
 }
@@ -280,36 +420,67 @@ func isImageURL(url string) bool {
 }
 
 // NEW: Update loading status
-func (m *model) updateLoading(stage string) {
-	m.status.Loading = true
-	m.status.LoadingStage = stage
-	m.status.StartTime = time.Now()
-	m.status.Error = ""
+func (t *Tab) updateLoading(stage string) {
+	t.Status.Loading = true
+	t.Status.LoadingStage = stage
+	t.Status.StartTime = time.Now()
+	t.Status.Error = ""
+	t.Status.RewrittenURL = ""
+	t.Status.Rewrites = nil
+	t.Status.Blocked = 0
+	t.Status.Notice = ""
 }
 
 // NEW: Complete loading with results
-func (m *model) completeLoading(
+func (t *Tab) completeLoading(
 	loadTime time.Duration,
 	pageSize int,
 	statusCode int,
 	linkCount int,
 ) {
-	m.status.Loading = false
-	m.status.LoadTime = loadTime
-	m.status.PageSize = pageSize
-	m.status.StatusCode = statusCode
-	m.status.LinkCount = linkCount
-	// You could add m.status.ImageCount = len(m.images) if you want
+	t.Status.Loading = false
+	t.Status.LoadTime = loadTime
+	t.Status.PageSize = pageSize
+	t.Status.StatusCode = statusCode
+	t.Status.LinkCount = linkCount
+	// You could add t.Status.ImageCount = len(t.Images) if you want
 }
 
 // NEW: Set error status
-func (m *model) setError(err string) {
-	m.status.Loading = false
-	m.status.Error = err
-	m.status.LoadTime = 0
+func (t *Tab) setError(err string) {
+	t.Status.Loading = false
+	t.Status.Error = err
+	t.Status.LoadTime = 0
+	t.Status.Notice = ""
+}
+
+// clearOverlays hides whatever list or overlay the tab shows over its page
+func (t *Tab) clearOverlays() {
+	t.ShowImages = false
+	t.ShowHistory = false
+	t.ShowBookmarks = false
+	t.ShowSearch = false
+	t.ShowTOC = false
+	t.ShowClosed = false
+	t.ShowTabs = false
+	t.ShowFind = false
+	t.ShowFeeds = false
+	t.ShowLater = false
 }
 
-func (m *model) openImageExternally(imageURL string) tea.Cmd {
+// overlayShown reports whether the viewport shows an overlay, not the page
+func (t *Tab) overlayShown() bool {
+	return t.ShowImages || t.ShowHistory || t.ShowBookmarks || t.ShowSearch || t.ShowTOC ||
+		t.ShowClosed || t.ShowTabs || t.ShowFind || t.ShowFeeds || t.ShowLater
+}
+
+// Show a message in the status panel in place of the page metrics
+func (t *Tab) setNotice(notice string) {
+	t.Status.Error = ""
+	t.Status.Notice = notice
+}
+
+func (m *model) openImageExternally(imageURL string, tabID int) tea.Cmd {
 	return func() tea.Msg {
 		var cmd *exec.Cmd
 
@@ -333,78 +504,113 @@ func (m *model) openImageExternally(imageURL string) tea.Cmd {
 					return nil
 				}
 			}
-			return errorMsg{err: fmt.Errorf("failed to open image with any viewer")}
+			return errorMsg{err: fmt.Errorf("failed to open image with any viewer"), tabID: tabID}
 		}
 		return nil
 	}
 }
 
 func fetchContentWithLinks(pageURL string, tabID int) tea.Cmd {
+	if id, ok := strings.CutPrefix(pageURL, laterScheme); ok {
+		return loadLaterSnapshot(id, tabID)
+	}
+	fetchURL, rewrites := rewriter.Rewrite(pageURL)
+	filter := contentFilter.forPage(fetchURL)
 	return func() tea.Msg {
 		start := time.Now()
-		doc, err := fetchHTML(pageURL)
+		doc, err := fetchHTML(fetchURL)
 		loadTime := time.Since(start)
 
 		if err != nil {
-			return errorMsg{err: err, tabID: tabID}
+			return errorMsg{err: rewrittenError(err, fetchURL, rewrites), tabID: tabID}
 		}
 
-		rawContent, links, images := extractContentWithLinks(doc, pageURL)
+		blocked := filter.Apply(doc, fetchURL)
+		page := extractPage(doc, fetchURL, false)
+		feeds := allowedFeeds(page.Feeds, filter, fetchURL)
+		rawContent := page.markdown
 		pageSize := len(rawContent)
 
 		// DEBUG: Check what's being extracted
 		log.Printf("DEBUG: Found %d images, %d links, content length: %d",
-			len(images), len(links), len(rawContent))
+			len(page.images), len(page.links), len(rawContent))
 
-		styledContent, err := renderWithStyle(rawContent)
+		styledContent, anchors, err := renderWithAnchors(rawContent, page.anchors)
 		if err != nil {
 			return errorMsg{err: err, tabID: tabID}
 		}
 
 		return fetchContentMsg{
-			content:    styledContent,
-			links:      links,
-			images:     images,
-			tabID:      tabID,
-			loadTime:   loadTime,
-			pageSize:   pageSize,
-			statusCode: 200,
+			title:        page.Title,
+			content:      styledContent,
+			markdown:     rawContent,
+			anchors:      anchors,
+			links:        page.links,
+			images:       page.images,
+			tabID:        tabID,
+			url:          pageURL,
+			loadTime:     loadTime,
+			pageSize:     pageSize,
+			statusCode:   200,
+			rewrittenURL: fetchURL,
+			rewrites:     rewrites,
+			blocked:      blocked,
+			feeds:        feeds,
 		}
 	}
 }
 
 func fetchContentWithReaderMode(pageURL string, tabID int) tea.Cmd {
+	if id, ok := strings.CutPrefix(pageURL, laterScheme); ok {
+		return loadLaterSnapshot(id, tabID)
+	}
+	fetchURL, rewrites := rewriter.Rewrite(pageURL)
+	filter := contentFilter.forPage(fetchURL)
 	return func() tea.Msg {
 		start := time.Now()
-		doc, err := fetchHTML(pageURL)
+		doc, err := fetchHTML(fetchURL)
 		loadTime := time.Since(start)
 
 		if err != nil {
-			return errorMsg{err: err, tabID: tabID}
+			return errorMsg{err: rewrittenError(err, fetchURL, rewrites), tabID: tabID}
 		}
 
-		rawContent, links := extractReaderContent(doc, pageURL)
+		blocked := filter.Apply(doc, fetchURL)
+		page := extractPage(doc, fetchURL, true)
+		feeds := allowedFeeds(page.Feeds, filter, fetchURL)
+		rawContent := page.markdown
 		pageSize := len(rawContent)
 
-		styledContent, err := renderWithStyle(rawContent)
+		styledContent, anchors, err := renderWithAnchors(rawContent, page.anchors)
 		if err != nil {
 			return errorMsg{err: err, tabID: tabID}
 		}
 
 		return fetchContentMsg{
-			content:    styledContent,
-			links:      links,
-			tabID:      tabID,
-			loadTime:   loadTime,
-			pageSize:   pageSize,
-			statusCode: 200,
+			title:        page.Title,
+			content:      styledContent,
+			markdown:     rawContent,
+			anchors:      anchors,
+			links:        page.links,
+			images:       page.images,
+			tabID:        tabID,
+			url:          pageURL,
+			loadTime:     loadTime,
+			pageSize:     pageSize,
+			statusCode:   200,
+			readerMode:   true,
+			rewrittenURL: fetchURL,
+			rewrites:     rewrites,
+			blocked:      blocked,
+			feeds:        feeds,
 		}
 	}
 }
 
 // NEW: Render status panel (bottom panel)
 func (m *model) renderStatusPanel() string {
-	status := m.status
+	activeTab := m.activeTabPtr()
+	status := activeTab.Status
 
 	var statusText string
 
@@ -415,6 +621,8 @@ func (m *model) renderStatusPanel() string {
 	} else if status.Error != "" {
 		// Show error
 		statusText = fmt.Sprintf("❌ %s", status.Error)
+	} else if status.Notice != "" {
+		statusText = status.Notice
 	} else if status.LoadTime > 0 {
 		// Show success status with metrics
 		if status.StatusCode != 200 {
@@ -428,11 +636,23 @@ func (m *model) renderStatusPanel() string {
 				status.LoadTime.Round(time.Millisecond),
 				status.PageSize/1024,
 				status.LinkCount,
-				len(m.images))
+				len(activeTab.Images))
+		}
+		if len(status.Rewrites) > 0 {
+			rewritten := stripScheme(status.RewrittenURL)
+			if len(rewritten) > 40 {
+				rewritten = rewritten[:37] + "..."
+			}
+			statusText += fmt.Sprintf(" | ↪️ %s via %s", rewritten, strings.Join(status.Rewrites, ", "))
+		}
+		if status.Blocked > 0 {
+			statusText += fmt.Sprintf(" | 🛡️ %d blocked", status.Blocked)
+		}
+		if len(activeTab.Feeds) > 0 {
+			statusText += " | 📡 " + m.feedIndicator(activeTab)
 		}
 	} else {
 		// Ready state
-		activeTab := m.activeTabPtr()
 		if activeTab != nil && len(activeTab.History) > 0 && activeTab.CurrentPos >= 0 {
 			statusText = "✅ Ready"
 		} else {
@@ -440,11 +660,17 @@ func (m *model) renderStatusPanel() string {
 		}
 	}
 
+	background := lipgloss.Color("236")
+	if activeTab.Private {
+		statusText = "🕶️ Private | " + statusText
+		background = privateColor
+	}
+
 	return lipgloss.NewStyle().
 		Foreground(lipgloss.Color("255")).
-		Background(lipgloss.Color("236")).
+		Background(background).
 		Padding(0, 1).
-		Width(m.viewport.Width).
+		Width(m.width).
 		Align(lipgloss.Left).
 		Render(statusText)
 }
@@ -455,8 +681,7 @@ func (m *model) renderTabBar() string {
 		return ""
 	}
 
-	var tabBar strings.Builder
-
+	labels := make([]string, len(m.tabs))
 	for i, tab := range m.tabs {
 		title := tab.Title
 		if title == "" {
@@ -465,29 +690,91 @@ func (m *model) renderTabBar() string {
 		if len(title) > 15 {
 			title = title[:12] + "..."
 		}
+		if tab.Pinned {
+			title = "📌 " + title
+		}
+		if tab.Private {
+			title = "🕶️ " + title
+		}
+		// Tabs loading in the background show a spinner
+		if tab.Status.Loading {
+			title = m.spinner.View() + " " + title
+		}
 
-		if i == m.activeTab {
-			tabBar.WriteString(lipgloss.NewStyle().
+		switch {
+		case i == m.activeTab && tab.Private:
+			labels[i] = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("255")).
+				Background(privateColor).
+				Padding(0, 1).
+				Render(fmt.Sprintf("%d: %s", i+1, title))
+		case i == m.activeTab:
+			labels[i] = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("255")).
 				Background(lipgloss.Color("62")).
 				Padding(0, 1).
-				Render(fmt.Sprintf("%d: %s", i+1, title)))
-		} else {
-			tabBar.WriteString(lipgloss.NewStyle().
+				Render(fmt.Sprintf("%d: %s", i+1, title))
+		case tab.Private:
+			labels[i] = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("176")).
+				Background(lipgloss.Color("235")).
+				Padding(0, 1).
+				Render(fmt.Sprintf("%d: %s", i+1, title))
+		default:
+			labels[i] = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("240")).
 				Background(lipgloss.Color("235")).
 				Padding(0, 1).
-				Render(fmt.Sprintf("%d: %s", i+1, title)))
-		}
-
-		if i < len(m.tabs)-1 {
-			tabBar.WriteString(" ")
+				Render(fmt.Sprintf("%d: %s", i+1, title))
 		}
 	}
 
+	first, last := m.visibleTabs(labels)
+	var tabBar strings.Builder
+	if first > 0 {
+		tabBar.WriteString("‹ ")
+	}
+	tabBar.WriteString(strings.Join(labels[first:last+1], " "))
+	if last < len(labels)-1 {
+		tabBar.WriteString(" ›")
+	}
 	return tabBar.String()
 }
 
+// visibleTabs picks the range of tab labels that fits the terminal width,
+// always including the active tab
+func (m *model) visibleTabs(labels []string) (int, int) {
+	available := m.width
+	total := len(labels) - 1
+	for _, label := range labels {
+		total += lipgloss.Width(label)
+	}
+	if available <= 0 || total <= available {
+		return 0, len(labels) - 1
+	}
+
+	// Leave room for the scroll indicators on both sides
+	available -= 4
+	first, last := m.activeTab, m.activeTab
+	used := lipgloss.Width(labels[m.activeTab])
+	for {
+		grew := false
+		if last+1 < len(labels) && used+1+lipgloss.Width(labels[last+1]) <= available {
+			last++
+			used += 1 + lipgloss.Width(labels[last])
+			grew = true
+		}
+		if first > 0 && used+1+lipgloss.Width(labels[first-1]) <= available {
+			first--
+			used += 1 + lipgloss.Width(labels[first])
+			grew = true
+		}
+		if !grew {
+			return first, last
+		}
+	}
+}
+
 // Enhanced status view
 func (m *model) statusView() string {
 	activeTab := m.activeTabPtr()
@@ -498,7 +785,7 @@ func (m *model) statusView() string {
 	status := "🌐 Terminal Browser"
 
 	if len(activeTab.History) > 0 && activeTab.CurrentPos >= 0 {
-		currentURL := activeTab.History[activeTab.CurrentPos]
+		currentURL := activeTab.History[activeTab.CurrentPos].URL
 		if len(currentURL) > 30 {
 			currentURL = currentURL[:27] + "..."
 		}
@@ -508,7 +795,7 @@ func (m *model) statusView() string {
 			status += " | ⭐"
 		}
 
-		if m.readerMode {
+		if activeTab.ReaderMode {
 			status += " | 📖"
 		}
 	}
@@ -525,11 +812,17 @@ func (m *model) statusView() string {
 
 	status += " | " + navHints
 
+	background := lipgloss.Color("62")
+	if activeTab.Private {
+		status = "🕶️ Private | " + status
+		background = privateColor
+	}
+
 	return lipgloss.NewStyle().
 		Foreground(lipgloss.Color("255")).
-		Background(lipgloss.Color("62")).
+		Background(background).
 		Padding(0, 1).
-		Width(m.viewport.Width).
+		Width(m.width).
 		Align(lipgloss.Left).
 		Render(status)
 }
@@ -573,8 +866,11 @@ func shouldIncludeLink(text, href string) bool {
 	if isNavigationText(text) {
 		return false
 	}
+	// Bare "#" is a script hook; "#section" links are real fragment targets
+	if href == "#" {
+		return false
+	}
 	skipURLs := []string{
-		"#",
 		"javascript:",
 		"mailto:",
 		"tel:",
@@ -595,55 +891,6 @@ func shouldIncludeLink(text, href string) bool {
 	return true
 }
 
-func performSearch(query string) tea.Cmd {
-	return func() tea.Msg {
-		searchURL := fmt.Sprintf("https://html.duckduckgo.com/html/?q=%s", url.QueryEscape(query))
-		doc, err := fetchHTML(searchURL)
-		if err != nil {
-			return errorMsg{err: err}
-		}
-		var results []SearchResult
-		resultCounter := 1
-		doc.Find(".result").Each(func(i int, s *goquery.Selection) {
-			if resultCounter > 10 {
-				return
-			}
-			titleElem := s.Find(".result__a")
-			title := strings.TrimSpace(titleElem.Text())
-			link, _ := titleElem.Attr("href")
-			snippet := strings.TrimSpace(s.Find(".result__snippet").Text())
-			if title != "" && link != "" {
-				if strings.HasPrefix(link, "//duckduckgo.com/l/") {
-					if parsed, err := url.Parse(link); err == nil {
-						if realURL := parsed.Query().Get("uddg"); realURL != "" {
-							link = realURL
-						}
-					}
-				}
-				results = append(results, SearchResult{
-					Number:  resultCounter,
-					Title:   title,
-					URL:     link,
-					Snippet: snippet,
-				})
-				resultCounter++
-			}
-		})
-		if len(results) == 0 {
-			results = append(results, SearchResult{
-				Number:  1,
-				Title:   fmt.Sprintf("Search for: %s", query),
-				URL:     fmt.Sprintf("https://www.google.com/search?q=%s", url.QueryEscape(query)),
-				Snippet: "Click to view search results on Google",
-			})
-		}
-		return searchResultsMsg{
-			query:   query,
-			results: results,
-		}
-	}
-}
-
 func cleanURL(url string) string {
 	// Remove newlines and carriage returns
 	url = strings.ReplaceAll(url, "\n", "")
@@ -692,16 +939,21 @@ func resolveURL(baseURL, href string) string {
 	return base.ResolveReference(ref).String()
 }
 
+// Sent with every request so sites serve the same pages as to a desktop browser
+const userAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
 func fetchHTML(url string) (*goquery.Document, error) {
-	client := &http.Client{}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
-	req.Header.Set(
-		"User-Agent",
-		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-	)
+	return fetchDocument(req)
+}
+
+// fetchDocument sends req with browser-like headers and parses the response
+func fetchDocument(req *http.Request) (*goquery.Document, error) {
+	client := &http.Client{}
+	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set(
 		"Accept",
 		"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
@@ -739,12 +991,34 @@ func (m *model) Init() tea.Cmd {
 	return tea.Batch(
 		tea.EnterAltScreen,
 		textinput.Blink,
+		m.restoreStartupSession(),
+		m.scheduleSessionSave(),
+		m.startupFeedRefresh(),
+		m.scheduleFeedRefresh(),
 	)
 }
 
 func main() {
 	// Call ParseFlags ONLY here
 	config := ParseFlags()
+	for _, extractor := range config.Extractors {
+		if extractor.Root == "" || len(extractor.Hosts) == 0 {
+			log.Printf("Skipping extractor %q: it needs hosts and a root selector", extractor.Name)
+			continue
+		}
+		registerExtractor(extractor)
+	}
+	rewriter = newRewriter(config.RewriteRules, config.EnableRewrites)
+	linkCleaner = newLinkCleaner(config.CleanLinks, config.TrackingParams, config.RedirectWrappers)
+	contentFilter = loadContentFilter(config.FilterLists, config.FilterWhitelist,
+		resolvePaths(config).data("filter-whitelist.json"))
+	laterDir = resolvePaths(config).data("later")
+	if config.Dump.URL != "" || config.Dump.Format != "" {
+		os.Exit(runDump(config.Dump, os.Stdout))
+	}
+	if config.RemoteArgs != nil {
+		os.Exit(runRemote(remoteSocketPath(resolvePaths(config)), config.RemoteArgs))
+	}
 	// Pass config to InitialModel
 	m := InitialModel(config)
 
@@ -759,7 +1033,17 @@ func main() {
 
 	p := tea.NewProgram(&m, opts...)
 
+	if config.EnableRemote {
+		if stop := startRemoteServer(remoteSocketPath(m.paths), p); stop != nil {
+			defer stop()
+		}
+	}
+
 	if _, err := p.Run(); err != nil {
 		log.Fatal("Error running program:", err)
 	}
+
+	if _, err := m.saveSession(""); err != nil {
+		log.Printf("Error saving session: %v", err)
+	}
 }
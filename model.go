@@ -1,5 +1,25 @@
 package main
 
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Closed tabs kept around for reopening
+const maxClosedTabs = 20
+
+// closedTab remembers a closed tab and where it was
+type closedTab struct {
+	Tab      Tab
+	Index    int
+	ClosedAt time.Time
+}
+
 // Get the active tab
 func (m *model) activeTabPtr() *Tab {
 	if len(m.tabs) == 0 {
@@ -8,58 +28,235 @@ func (m *model) activeTabPtr() *Tab {
 	return &m.tabs[m.activeTab]
 }
 
-// Create a new tab
-func (m *model) newTab(url string) {
-	newTabID := len(m.tabs)
+// Find a tab by its ID. Returns nil if it has been closed.
+func (m *model) tabByID(id int) *Tab {
+	for i := range m.tabs {
+		if m.tabs[i].ID == id {
+			return &m.tabs[i]
+		}
+	}
+	return nil
+}
+
+// Find the index of a tab by its ID, or -1
+func (m *model) tabIndex(id int) int {
+	for i := range m.tabs {
+		if m.tabs[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Create a new tab after the existing ones and return its index.
+// Tab IDs are never reused so late fetch results can't land in the wrong tab.
+func (m *model) newTab(url string) int {
 	newTab := Tab{
-		ID:         newTabID,
+		ID:         m.nextTabID,
 		Title:      "New Tab",
 		URL:        url,
 		Content:    "🔄 Loading...",
+		Display:    "🔄 Loading...",
 		Links:      []Link{},
+		Images:     []ImageInfo{},
 		ReaderMode: false,
-		History:    []string{},
+		History:    []HistoryItem{},
 		CurrentPos: -1,
 	}
+	m.nextTabID++
 
 	if url != "" {
 		newTab.navigateTo(url)
 	}
 
 	m.tabs = append(m.tabs, newTab)
-	m.activeTab = newTabID
+	return len(m.tabs) - 1
 }
 
 // Close a tab
-func (m *model) closeTab(tabID int) {
+func (m *model) closeTab(index int) {
 	if len(m.tabs) <= 1 {
 		return
 	}
 
-	m.tabs = append(m.tabs[:tabID], m.tabs[tabID+1:]...)
-	for i := range m.tabs {
-		m.tabs[i].ID = i
+	m.closedTabs = append(m.closedTabs, closedTab{
+		Tab:      m.tabs[index],
+		Index:    index,
+		ClosedAt: time.Now(),
+	})
+	if len(m.closedTabs) > maxClosedTabs {
+		m.closedTabs = m.closedTabs[len(m.closedTabs)-maxClosedTabs:]
 	}
 
+	m.tabs = append(m.tabs[:index], m.tabs[index+1:]...)
+
 	if m.activeTab >= len(m.tabs) {
 		m.activeTab = len(m.tabs) - 1
-	} else if m.activeTab >= tabID {
+	} else if m.activeTab > index || (m.activeTab == index && index > 0) {
 		m.activeTab--
 	}
+	m.forgetPrivateTabs()
 }
 
-// Switch to a tab
-func (m *model) switchTab(tabID int) {
-	if tabID >= 0 && tabID < len(m.tabs) {
-		m.activeTab = tabID
-		tab := m.tabs[m.activeTab]
-		m.content = tab.Content
-		m.links = tab.Links
-		m.readerMode = tab.ReaderMode
+// Reopen a closed tab at its original position. i indexes m.closedTabs.
+func (m *model) reopenTab(i int) bool {
+	if i < 0 || i >= len(m.closedTabs) {
+		return false
+	}
+	closed := m.closedTabs[i]
+	m.closedTabs = append(m.closedTabs[:i], m.closedTabs[i+1:]...)
+
+	// A fetch still running at close time was dropped; load it again
+	if closed.Tab.Status.Loading {
+		closed.Tab.Status.Loading = false
+		closed.Tab.Content = ""
+	}
+
+	index := min(closed.Index, len(m.tabs))
+	// Keep pinned tabs grouped at the left
+	if closed.Tab.Pinned {
+		index = min(index, m.pinnedCount())
+	} else {
+		index = max(index, m.pinnedCount())
+	}
+	m.tabs = append(m.tabs[:index], append([]Tab{closed.Tab}, m.tabs[index:]...)...)
+	if m.activeTab >= index {
+		m.activeTab++
+	}
+	m.switchTab(index)
+	return true
+}
 
-		if m.ready {
-			m.viewport.SetContent(m.content)
-			m.viewport.GotoTop()
+// pinnedCount returns how many tabs are pinned. Pinned tabs always come first.
+func (m *model) pinnedCount() int {
+	count := 0
+	for _, tab := range m.tabs {
+		if tab.Pinned {
+			count++
+		}
+	}
+	return count
+}
+
+// Move the tab at from so it ends up at index to, keeping the same tab active
+func (m *model) moveTab(from, to int) {
+	if from == to || from < 0 || from >= len(m.tabs) || to < 0 || to >= len(m.tabs) {
+		return
+	}
+	activeID := m.tabs[m.activeTab].ID
+	tab := m.tabs[from]
+	m.tabs = append(m.tabs[:from], m.tabs[from+1:]...)
+	m.tabs = append(m.tabs[:to], append([]Tab{tab}, m.tabs[to:]...)...)
+	for i := range m.tabs {
+		if m.tabs[i].ID == activeID {
+			m.activeTab = i
 		}
 	}
 }
+
+// Move the active tab one place left (dir < 0) or right without leaving
+// its pinned or unpinned group
+func (m *model) shiftActiveTab(dir int) bool {
+	lo, hi := m.pinnedCount(), len(m.tabs)-1
+	if m.tabs[m.activeTab].Pinned {
+		lo, hi = 0, m.pinnedCount()-1
+	}
+	to := m.activeTab + dir
+	if to < lo || to > hi {
+		return false
+	}
+	m.moveTab(m.activeTab, to)
+	return true
+}
+
+// Pin or unpin a tab, moving it to the edge of the pinned group
+func (m *model) togglePin(index int) {
+	tab := &m.tabs[index]
+	tab.Pinned = !tab.Pinned
+	if tab.Pinned {
+		m.moveTab(index, m.pinnedCount()-1)
+	} else {
+		m.moveTab(index, m.pinnedCount())
+	}
+}
+
+// Copy a tab with its whole history next to the original and return the
+// copy's index
+func (m *model) duplicateTab(index int) int {
+	dup := m.tabs[index]
+	dup.ID = m.nextTabID
+	m.nextTabID++
+	dup.Pinned = false
+	dup.History = append([]HistoryItem(nil), dup.History...)
+	if dup.Status.Loading {
+		// Our copy has no fetch of its own; load it when switched to
+		dup.Status = StatusInfo{}
+		dup.Content = ""
+		dup.restoreScroll = true
+	}
+
+	at := max(index+1, m.pinnedCount())
+	m.tabs = append(m.tabs[:at], append([]Tab{dup}, m.tabs[at:]...)...)
+	if m.activeTab >= at {
+		m.activeTab++
+	}
+	return at
+}
+
+// filterTabs returns the indices of tabs matching query, best match first
+func (m *model) filterTabs(query string) []int {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var indices []int
+	scores := make(map[int]float64)
+	for i, tab := range m.tabs {
+		urlScore, urlOK := fuzzyScore(query, strings.ToLower(stripScheme(tab.URL)))
+		titleScore, titleOK := fuzzyScore(query, strings.ToLower(tab.Title))
+		if !urlOK && !titleOK {
+			continue
+		}
+		indices = append(indices, i)
+		scores[i] = math.Max(urlScore, titleScore)
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		return scores[indices[a]] > scores[indices[b]]
+	})
+	return indices
+}
+
+// Switch to a tab, remembering where the old one was scrolled to
+func (m *model) switchTab(index int) {
+	if index < 0 || index >= len(m.tabs) {
+		return
+	}
+	if current := m.activeTabPtr(); current != nil && m.ready {
+		current.DisplayOffset = m.viewport.YOffset
+	}
+	m.activeTab = index
+	tab := m.activeTabPtr()
+
+	if m.ready {
+		m.viewport.SetContent(tab.Display)
+		m.viewport.SetYOffset(tab.DisplayOffset)
+	}
+}
+
+// anyTabLoading reports whether some tab is still fetching
+func (m *model) anyTabLoading() bool {
+	for _, tab := range m.tabs {
+		if tab.Status.Loading {
+			return true
+		}
+	}
+	return false
+}
+
+// Keep the tab bar spinner turning while anything is loading
+func (m *model) handleSpinnerTick(msg spinner.TickMsg) (tea.Model, tea.Cmd) {
+	if !m.anyTabLoading() {
+		m.spinning = false
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return m, cmd
+}
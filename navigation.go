@@ -1,13 +1,54 @@
 package main
 
+import (
+	"encoding/json"
+	"strings"
+)
+
+// HistoryItem is one entry in a tab's back/forward stack, with enough
+// view state to put the reader back where they left off
+type HistoryItem struct {
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	YOffset    int    `json:"y_offset"`
+	ReaderMode bool   `json:"reader_mode"`
+	Fragment   string `json:"fragment,omitempty"`
+}
+
+// UnmarshalJSON also accepts the bare URL strings older sessions stored
+func (h *HistoryItem) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*h = newHistoryItem(url)
+		return nil
+	}
+	type plain HistoryItem
+	return json.Unmarshal(data, (*plain)(h))
+}
+
+// newHistoryItem splits a #fragment off the URL
+func newHistoryItem(url string) HistoryItem {
+	base, fragment, _ := strings.Cut(url, "#")
+	return HistoryItem{URL: base, Fragment: fragment}
+}
+
 // Tab navigation helper
 func (t *Tab) navigateTo(url string) {
 	if t.CurrentPos < len(t.History)-1 {
 		t.History = t.History[:t.CurrentPos+1]
 	}
-	t.History = append(t.History, url)
+	item := newHistoryItem(url)
+	t.History = append(t.History, item)
 	t.CurrentPos = len(t.History) - 1
-	t.URL = url
+	t.URL = item.URL
+}
+
+// currentEntry returns the history entry being displayed, if any
+func (t *Tab) currentEntry() *HistoryItem {
+	if t.CurrentPos < 0 || t.CurrentPos >= len(t.History) {
+		return nil
+	}
+	return &t.History[t.CurrentPos]
 }
 
 // Tab history helpers
@@ -22,13 +63,13 @@ func (t *Tab) canGoForward() bool {
 func (t *Tab) goBack() {
 	if t.canGoBack() {
 		t.CurrentPos--
-		t.URL = t.History[t.CurrentPos]
+		t.URL = t.History[t.CurrentPos].URL
 	}
 }
 
 func (t *Tab) goForward() {
 	if t.canGoForward() {
 		t.CurrentPos++
-		t.URL = t.History[t.CurrentPos]
+		t.URL = t.History[t.CurrentPos].URL
 	}
 }
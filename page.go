@@ -0,0 +1,289 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Page is the structured form of an extracted page, as printed by -json.
+// Content, links and images come from the same extractors the TUI uses.
+type Page struct {
+	URL        string            `json:"url"`
+	Title      string            `json:"title"`
+	Reader     bool              `json:"reader"`
+	Meta       map[string]string `json:"meta"`
+	Headings   []PageHeading     `json:"headings"`
+	Paragraphs []string          `json:"paragraphs"`
+	Links      []PageLink        `json:"links"`
+	Images     []PageImage       `json:"images"`
+	Forms      []PageForm        `json:"forms"`
+	Feeds      []PageFeed        `json:"feeds"`
+	Content    string            `json:"content"`           // Markdown as shown in the browser
+	Blocked    int               `json:"blocked,omitempty"` // elements and images removed by filter lists
+	Timings    PageTimings       `json:"timings"`
+
+	// What the TUI renders from: the content with anchor markers, and the
+	// extractor's own link, image and anchor lists
+	markdown string
+	anchors  []Anchor
+	links    []Link
+	images   []ImageInfo
+}
+
+type PageHeading struct {
+	Level int      `json:"level"`
+	Text  string   `json:"text"`
+	IDs   []string `json:"ids,omitempty"`
+}
+
+type PageLink struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+	URL    string `json:"url"`
+	Image  bool   `json:"image,omitempty"`
+}
+
+type PageImage struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Alt    string `json:"alt,omitempty"`
+	Type   string `json:"type"`
+	Link   string `json:"link,omitempty"` // where the image links to, if anywhere
+}
+
+type PageForm struct {
+	Action string      `json:"action"`
+	Method string      `json:"method"`
+	Fields []FormField `json:"fields"`
+}
+
+type FormField struct {
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+	Label string `json:"label,omitempty"`
+}
+
+// PageTimings are in milliseconds
+type PageTimings struct {
+	Fetch   float64 `json:"fetch_ms"`
+	Extract float64 `json:"extract_ms"`
+}
+
+// Meta tags copied into Page.Meta, besides every og: and twitter: property
+var pageMetaNames = []string{"description", "author", "keywords", "generator", "robots"}
+
+// fetchPage downloads pageURL and extracts it
+func fetchPage(pageURL string, reader bool) (Page, error) {
+	start := time.Now()
+	fetchURL, rewrites := rewriter.Rewrite(pageURL)
+	doc, err := fetchHTML(fetchURL)
+	if err != nil {
+		return Page{}, rewrittenError(err, fetchURL, rewrites)
+	}
+	fetched := time.Now()
+	filter := contentFilter.forPage(fetchURL)
+	blocked := filter.Apply(doc, fetchURL)
+	page := extractPage(doc, fetchURL, reader)
+	page.Blocked = blocked
+	page.Feeds = allowedFeeds(page.Feeds, filter, fetchURL)
+	page.Timings = PageTimings{
+		Fetch:   milliseconds(fetched.Sub(start)),
+		Extract: milliseconds(time.Since(fetched)),
+	}
+	return page, nil
+}
+
+// extractPage builds a Page from a parsed document with the extractor
+// registered for pageURL, or a generic one
+func extractPage(doc *goquery.Document, pageURL string, reader bool) Page {
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	meta := pageMeta(doc)
+	// Read before extraction, which may rewrite the document
+	forms := pageForms(doc, pageURL)
+	feeds := pageFeeds(doc, pageURL)
+
+	page := extractorFor(pageURL, reader).Extract(doc, pageURL)
+	page.URL = pageURL
+	page.Reader = reader
+	page.Meta = meta
+	page.Forms = forms
+	page.Feeds = feeds
+	if page.Title == "" {
+		page.Title = title
+	}
+	return page
+}
+
+// newPage fills in a Page from an extractor's output
+func newPage(pageURL, title, markdown string, links []Link, images []ImageInfo, anchors []Anchor) Page {
+	for i := range links {
+		links[i].FullURL = cleanLink(links[i].FullURL)
+	}
+	for i := range images {
+		if images[i].LinkURL != "" {
+			images[i].LinkURL = cleanLink(images[i].LinkURL)
+		}
+	}
+	page := Page{
+		URL:        pageURL,
+		Title:      title,
+		Headings:   []PageHeading{},
+		Paragraphs: []string{},
+		Links:      []PageLink{},
+		Images:     []PageImage{},
+		Forms:      []PageForm{},
+		markdown:   markdown,
+		anchors:    anchors,
+		links:      links,
+		images:     images,
+	}
+	page.Content = strings.TrimSpace(stripAnchorMarkers(markdown)) + "\n"
+	page.Paragraphs = paragraphs(page.Content)
+
+	for _, heading := range headings(anchors) {
+		page.Headings = append(page.Headings, PageHeading{Level: heading.Level, Text: heading.Text, IDs: heading.IDs})
+	}
+	for _, link := range links {
+		page.Links = append(page.Links, PageLink{
+			Number: link.Number,
+			Text:   link.Text,
+			URL:    link.FullURL,
+			Image:  link.IsImage,
+		})
+	}
+	for _, image := range images {
+		page.Images = append(page.Images, PageImage{
+			Number: image.Number,
+			URL:    image.URL,
+			Alt:    image.AltText,
+			Type:   image.Type,
+			Link:   image.LinkURL,
+		})
+	}
+	return page
+}
+
+// pageMeta collects the document language, canonical URL and useful meta tags
+func pageMeta(doc *goquery.Document) map[string]string {
+	meta := make(map[string]string)
+	if lang, ok := doc.Find("html").Attr("lang"); ok && lang != "" {
+		meta["lang"] = lang
+	}
+	if canonical, ok := doc.Find(`link[rel="canonical"]`).Attr("href"); ok && canonical != "" {
+		meta["canonical"] = canonical
+	}
+	doc.Find("meta[content]").Each(func(i int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		if name == "" {
+			name, _ = s.Attr("property")
+		}
+		name = strings.ToLower(name)
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+		for _, wanted := range pageMetaNames {
+			if name == wanted {
+				meta[name] = content
+				return
+			}
+		}
+		if strings.HasPrefix(name, "og:") || strings.HasPrefix(name, "twitter:") {
+			meta[name] = content
+		}
+	})
+	return meta
+}
+
+// pageForms lists the forms on the page with their fields
+func pageForms(doc *goquery.Document, pageURL string) []PageForm {
+	forms := []PageForm{}
+	doc.Find("form").Each(func(i int, s *goquery.Selection) {
+		method := strings.ToUpper(s.AttrOr("method", "GET"))
+		form := PageForm{
+			Action: resolveURL(pageURL, s.AttrOr("action", "")),
+			Method: method,
+			Fields: []FormField{},
+		}
+		s.Find("input, select, textarea, button").Each(func(j int, field *goquery.Selection) {
+			fieldType := goquery.NodeName(field)
+			switch fieldType {
+			case "input":
+				fieldType = strings.ToLower(field.AttrOr("type", "text"))
+			case "button":
+				fieldType = strings.ToLower(field.AttrOr("type", "submit"))
+			}
+			form.Fields = append(form.Fields, FormField{
+				Name:  field.AttrOr("name", ""),
+				Type:  fieldType,
+				Value: field.AttrOr("value", ""),
+				Label: fieldLabel(doc, field),
+			})
+		})
+		forms = append(forms, form)
+	})
+	return forms
+}
+
+// fieldLabel finds the text labelling a form field
+func fieldLabel(doc *goquery.Document, field *goquery.Selection) string {
+	if id, ok := field.Attr("id"); ok && id != "" {
+		label := doc.Find("label").FilterFunction(func(i int, l *goquery.Selection) bool {
+			return l.AttrOr("for", "") == id
+		})
+		if text := strings.TrimSpace(label.First().Text()); text != "" {
+			return text
+		}
+	}
+	if text := strings.TrimSpace(field.Closest("label").Text()); text != "" {
+		return text
+	}
+	if placeholder, ok := field.Attr("placeholder"); ok {
+		return placeholder
+	}
+	if label, ok := field.Attr("aria-label"); ok {
+		return label
+	}
+	if goquery.NodeName(field) == "button" {
+		return strings.TrimSpace(field.Text())
+	}
+	return ""
+}
+
+// paragraphs returns the plain text of the prose blocks in Markdown content,
+// skipping headings, lists, quotes, tables and code
+func paragraphs(markdown string) []string {
+	result := []string{}
+	for _, block := range strings.Split(markdown, "\n\n") {
+		block = strings.TrimSpace(block)
+		if !isProse(block) {
+			continue
+		}
+		if text := strings.TrimSpace(markdownToText(block)); text != "" {
+			result = append(result, strings.Join(strings.Fields(text), " "))
+		}
+	}
+	return result
+}
+
+// isProse reports whether a Markdown block is an ordinary paragraph
+func isProse(block string) bool {
+	if block == "" {
+		return false
+	}
+	for _, prefix := range []string{"#", "- ", "* ", "+ ", ">", "|", "```", "![", "---", "🖼️"} {
+		if strings.HasPrefix(block, prefix) {
+			return false
+		}
+	}
+	// Numbered list items
+	digits := strings.TrimLeft(block, "0123456789")
+	return len(digits) == len(block) || !strings.HasPrefix(digits, ". ")
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PageIndex is a full-text index over the text of visited pages. Each page
+// is kept as its own file in the cache directory, which doubles as the
+// cached snapshot; the inverted index is built in memory on first use.
+type PageIndex struct {
+	dir    string
+	loaded bool
+	pages  map[string]*indexedPage
+	terms  map[string]map[string]int // term -> URL -> occurrences
+
+	// keep decides whether a stored page may stay, so the index follows the
+	// history's retention and exclusions. Nil keeps everything.
+	keep func(url string, fetched time.Time) bool
+}
+
+// indexedPage is one page as stored on disk
+type indexedPage struct {
+	URL      string    `json:"url"`
+	Title    string    `json:"title"`
+	Fetched  time.Time `json:"fetched"`
+	Markdown string    `json:"markdown"`
+
+	text   string // Markdown with the markup stripped
+	length int    // number of terms
+}
+
+// pageHit is a ranked find-history result
+type pageHit struct {
+	Page    *indexedPage
+	Score   float64
+	Snippet string // Markdown with the matched terms in bold
+}
+
+// BM25 tuning
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+const (
+	maxPageHits   = 20
+	snippetRadius = 80
+)
+
+func newPageIndex(dir string) *PageIndex {
+	return &PageIndex{
+		dir:   dir,
+		pages: make(map[string]*indexedPage),
+		terms: make(map[string]map[string]int),
+	}
+}
+
+// pageFile is where a URL's page is stored
+func (x *PageIndex) pageFile(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(x.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load reads every stored page the first time the index is searched
+func (x *PageIndex) load() {
+	if x.loaded {
+		return
+	}
+	x.loaded = true
+	files, err := filepath.Glob(filepath.Join(x.dir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var page indexedPage
+		if err := json.Unmarshal(data, &page); err != nil {
+			log.Printf("Error reading indexed page %s: %v", file, err)
+			continue
+		}
+		if x.keep != nil && !x.keep(page.URL, page.Fetched) {
+			os.Remove(file)
+			continue
+		}
+		x.insert(&page)
+	}
+}
+
+// Prune deletes stored pages saved before cutoff without reading them.
+// Pages keep rejects for other reasons are dropped when the index loads.
+func (x *PageIndex) Prune(cutoff time.Time) {
+	entries, err := os.ReadDir(x.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+			if err := os.Remove(filepath.Join(x.dir, entry.Name())); err != nil {
+				log.Printf("Error pruning indexed page: %v", err)
+			}
+		}
+	}
+}
+
+// Add stores a page and indexes its text, replacing an older copy
+func (x *PageIndex) Add(url, title, markdown string) {
+	if url == "" || strings.TrimSpace(markdown) == "" {
+		return
+	}
+	page := &indexedPage{URL: url, Title: title, Fetched: time.Now(), Markdown: markdown}
+	data, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error indexing page: %v", err)
+		return
+	}
+	if err := os.MkdirAll(x.dir, 0o755); err != nil {
+		log.Printf("Error creating page index: %v", err)
+		return
+	}
+	if err := writeFileAtomic(x.pageFile(url), data); err != nil {
+		log.Printf("Error writing indexed page: %v", err)
+		return
+	}
+	// Before the first search there is nothing in memory to keep current
+	if x.loaded {
+		x.remove(url)
+		x.insert(page)
+	}
+}
+
+// Delete drops a page from the index and the disk
+func (x *PageIndex) Delete(url string) {
+	x.remove(url)
+	if err := os.Remove(x.pageFile(url)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error deleting indexed page: %v", err)
+	}
+}
+
+// Clear drops every page
+func (x *PageIndex) Clear() {
+	files, _ := filepath.Glob(filepath.Join(x.dir, "*.json"))
+	for _, file := range files {
+		os.Remove(file)
+	}
+	x.pages = make(map[string]*indexedPage)
+	x.terms = make(map[string]map[string]int)
+}
+
+// Cached reports when url was stored, without loading the index
+func (x *PageIndex) Cached(url string) (time.Time, bool) {
+	info, err := os.Stat(x.pageFile(url))
+	if err != nil || (x.keep != nil && !x.keep(url, info.ModTime())) {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// Page returns the stored copy of url
+func (x *PageIndex) Page(url string) *indexedPage {
+	x.load()
+	return x.pages[url]
+}
+
+func (x *PageIndex) insert(page *indexedPage) {
+	page.text = plainText(page.Markdown)
+	counts := make(map[string]int)
+	for _, term := range tokenize(page.Title + " " + page.text) {
+		counts[term]++
+		page.length++
+	}
+	for term, count := range counts {
+		if x.terms[term] == nil {
+			x.terms[term] = make(map[string]int)
+		}
+		x.terms[term][page.URL] = count
+	}
+	x.pages[page.URL] = page
+}
+
+func (x *PageIndex) remove(url string) {
+	page, ok := x.pages[url]
+	if !ok {
+		return
+	}
+	for _, term := range tokenize(page.Title + " " + page.text) {
+		delete(x.terms[term], url)
+		if len(x.terms[term]) == 0 {
+			delete(x.terms, term)
+		}
+	}
+	delete(x.pages, url)
+}
+
+// Search ranks pages against query with BM25. Pages containing every term
+// come first; title matches get a boost.
+func (x *PageIndex) Search(query string) []pageHit {
+	x.load()
+	terms := uniqueTerms(tokenize(query))
+	if len(terms) == 0 || len(x.pages) == 0 {
+		return nil
+	}
+
+	var totalLength int
+	for _, page := range x.pages {
+		totalLength += page.length
+	}
+	avgLength := float64(totalLength) / float64(len(x.pages))
+
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	for _, term := range terms {
+		postings := x.terms[term]
+		if len(postings) == 0 {
+			continue
+		}
+		n := float64(len(postings))
+		idf := math.Log(1 + (float64(len(x.pages))-n+0.5)/(n+0.5))
+		for url, count := range postings {
+			page := x.pages[url]
+			tf := float64(count)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(page.length)/avgLength)
+			scores[url] += idf * tf * (bm25K1 + 1) / (tf + norm)
+			if strings.Contains(strings.ToLower(page.Title), term) {
+				scores[url] += idf
+			}
+			matched[url]++
+		}
+	}
+
+	hits := make([]pageHit, 0, len(scores))
+	for url, score := range scores {
+		hits = append(hits, pageHit{Page: x.pages[url], Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		mi, mj := matched[hits[i].Page.URL], matched[hits[j].Page.URL]
+		if mi != mj {
+			return mi > mj
+		}
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > maxPageHits {
+		hits = hits[:maxPageHits]
+	}
+	for i := range hits {
+		hits[i].Snippet = highlightSnippet(hits[i].Page.text, terms)
+	}
+	return hits
+}
+
+// tokenize lowercases text and splits it into words of two or more runes
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) >= 2 {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+var (
+	markdownLinkPattern   = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	markdownMarkupPattern = regexp.MustCompile("[*_`#>|\\[\\]]+")
+)
+
+// plainText strips Markdown down to the words a reader would see
+func plainText(markdown string) string {
+	text := markdownLinkPattern.ReplaceAllString(markdown, "$1")
+	text = markdownMarkupPattern.ReplaceAllString(text, " ")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// highlightSnippet cuts the part of text around the densest run of matches
+// and puts the matched words in bold
+func highlightSnippet(text string, terms []string) string {
+	lower, offsets := foldCase(text)
+	best, bestCount := -1, 0
+	for _, term := range terms {
+		for start := 0; ; {
+			i := strings.Index(lower[start:], term)
+			if i < 0 {
+				break
+			}
+			pos := start + i
+			window := lower[max(pos-snippetRadius, 0):min(pos+snippetRadius, len(lower))]
+			count := 0
+			for _, t := range terms {
+				count += strings.Count(window, t)
+			}
+			if count > bestCount {
+				best, bestCount = pos, count
+			}
+			start = pos + len(term)
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	// Matches were found in lower, which can differ in length from text
+	best = offsets[best]
+
+	from, to := max(best-snippetRadius, 0), min(best+snippetRadius, len(text))
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+	// Keep whole words at both ends
+	if from > 0 {
+		if space := strings.IndexByte(text[from:to], ' '); space >= 0 {
+			from += space + 1
+		}
+	}
+	if to < len(text) {
+		if space := strings.LastIndexByte(text[from:to], ' '); space > 0 {
+			to = from + space
+		}
+	}
+
+	var snippet strings.Builder
+	if from > 0 {
+		snippet.WriteString("…")
+	}
+	for i, word := range strings.Fields(text[from:to]) {
+		if i > 0 {
+			snippet.WriteByte(' ')
+		}
+		if matchesTerm(word, terms) {
+			snippet.WriteString("**" + word + "**")
+		} else {
+			snippet.WriteString(word)
+		}
+	}
+	if to < len(text) {
+		snippet.WriteString("…")
+	}
+	return snippet.String()
+}
+
+// foldCase lowercases text rune by rune, like strings.ToLower, and returns
+// for every byte of the result the offset of the rune in text it came from
+func foldCase(text string) (string, []int) {
+	var lower strings.Builder
+	offsets := make([]int, 0, len(text)+1)
+	for i, r := range text {
+		n := lower.Len()
+		lower.WriteRune(unicode.ToLower(r))
+		for range lower.Len() - n {
+			offsets = append(offsets, i)
+		}
+	}
+	offsets = append(offsets, len(text))
+	return lower.String(), offsets
+}
+
+func matchesTerm(word string, terms []string) bool {
+	for _, token := range tokenize(word) {
+		for _, term := range terms {
+			if strings.HasPrefix(token, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
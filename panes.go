@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type splitMode int
+
+const (
+	splitNone       splitMode = iota
+	splitHorizontal           // panes stacked top and bottom
+	splitVertical             // panes side by side
+)
+
+// Rows taken by the tab bar, status line and URL bar above the panes, and
+// the status panel below them
+const (
+	headerHeight = 4
+	footerHeight = 2
+)
+
+const (
+	minSplitRatio   = 0.2
+	maxSplitRatio   = 0.8
+	splitResizeStep = 0.05
+)
+
+// pane binds a viewport to a tab. While a pane has focus its viewport lives
+// in m.viewport, so everything else keeps working on the focused pane.
+type pane struct {
+	tabID    int
+	viewport viewport.Model
+}
+
+// paneViewport returns the live viewport of pane i
+func (m *model) paneViewport(i int) *viewport.Model {
+	if i == m.focusedPane {
+		return &m.viewport
+	}
+	return &m.panes[i].viewport
+}
+
+// unfocusedPaneFor returns the viewport of the other pane if it shows tab
+func (m *model) unfocusedPaneFor(tab *Tab) *viewport.Model {
+	if m.split == splitNone {
+		return nil
+	}
+	other := 1 - m.focusedPane
+	if m.panes[other].tabID != tab.ID {
+		return nil
+	}
+	return &m.panes[other].viewport
+}
+
+// openSplit shows a second pane next to the current one. The new pane gets
+// the next tab, or a fresh one when only one tab is open. Both panes never
+// show the same tab, so the split is refused when no second tab can be had.
+func (m *model) openSplit(mode splitMode) {
+	if m.split != splitNone {
+		m.split = mode
+		m.layoutPanes()
+		return
+	}
+
+	otherIndex := (m.activeTab + 1) % len(m.tabs)
+	if len(m.tabs) == 1 {
+		if len(m.tabs) >= m.config.MaxTabs {
+			m.activeTabPtr().setError(fmt.Sprintf("Splitting needs a second tab; max tabs limit: %d", m.config.MaxTabs))
+			return
+		}
+		otherIndex = m.newTab("")
+		m.tabs[otherIndex].Display = "🌐 New Tab"
+	}
+	other := &m.tabs[otherIndex]
+
+	m.panes[0] = pane{tabID: m.activeTabPtr().ID}
+	m.panes[1] = pane{tabID: other.ID, viewport: viewport.New(0, 0)}
+	m.panes[1].viewport.SetContent(other.Display)
+	m.focusedPane = 0
+	m.split = mode
+	m.splitRatio = 0.5
+	m.layoutPanes()
+	m.panes[1].viewport.SetYOffset(other.DisplayOffset)
+}
+
+// focusPane moves focus to pane i and makes its tab the active one
+func (m *model) focusPane(i int) {
+	if m.split == splitNone || i == m.focusedPane {
+		return
+	}
+	if current := m.activeTabPtr(); current != nil {
+		current.DisplayOffset = m.viewport.YOffset
+	}
+	m.panes[m.focusedPane].viewport = m.viewport
+	m.focusedPane = i
+	m.viewport = m.panes[i].viewport
+	if index := m.tabIndex(m.panes[i].tabID); index >= 0 {
+		m.activeTab = index
+	}
+}
+
+// closePane removes pane i, leaving the other one full size
+func (m *model) closePane(i int) {
+	if m.split == splitNone {
+		return
+	}
+	m.focusPane(1 - i)
+	m.split = splitNone
+	m.focusedPane = 0
+	m.panes = [2]pane{}
+	m.layoutPanes()
+}
+
+// resizePane grows (delta > 0) or shrinks the focused pane
+func (m *model) resizePane(delta float64) {
+	if m.split == splitNone {
+		return
+	}
+	if m.focusedPane == 1 {
+		delta = -delta
+	}
+	m.splitRatio = min(max(m.splitRatio+delta, minSplitRatio), maxSplitRatio)
+	m.layoutPanes()
+}
+
+// syncPanes keeps the panes bound to live tabs after tabs were switched,
+// closed or reopened
+func (m *model) syncPanes() {
+	if m.split == splitNone {
+		return
+	}
+	if tab := m.activeTabPtr(); tab != nil {
+		focused, other := &m.panes[m.focusedPane], &m.panes[1-m.focusedPane]
+		if other.tabID == tab.ID {
+			// Switching to the tab shown in the other pane swaps the panes
+			other.tabID = focused.tabID
+			if previous := m.tabByID(other.tabID); previous != nil {
+				other.viewport.SetContent(previous.Display)
+				other.viewport.SetYOffset(previous.DisplayOffset)
+			}
+		}
+		focused.tabID = tab.ID
+	}
+	if m.tabByID(m.panes[1-m.focusedPane].tabID) == nil {
+		m.closePane(1 - m.focusedPane)
+	}
+}
+
+// layoutPanes sizes and positions every pane's viewport for the window
+func (m *model) layoutPanes() {
+	if !m.ready {
+		return
+	}
+	width := m.width
+	height := max(m.height-headerHeight-footerHeight, 0)
+
+	switch m.split {
+	case splitNone:
+		m.viewport.Width = width
+		m.viewport.Height = height
+		m.viewport.YPosition = headerHeight
+
+	case splitHorizontal:
+		// Each pane has a one line title bar above its viewport
+		first := int(float64(height) * m.splitRatio)
+		sizes := [2]int{first, height - first}
+		y := headerHeight
+		for i := range m.panes {
+			vp := m.paneViewport(i)
+			vp.Width = width
+			vp.Height = max(sizes[i]-1, 0)
+			vp.YPosition = y + 1
+			y += sizes[i]
+		}
+
+	case splitVertical:
+		// A one column separator sits between the panes
+		first := int(float64(width) * m.splitRatio)
+		sizes := [2]int{first, width - first - 1}
+		for i := range m.panes {
+			vp := m.paneViewport(i)
+			vp.Width = max(sizes[i], 0)
+			vp.Height = max(height-1, 0)
+			vp.YPosition = headerHeight + 1
+		}
+	}
+}
+
+// renderPanes draws both panes with a title bar marking the focused one
+func (m *model) renderPanes() string {
+	var blocks [2]string
+	for i := range m.panes {
+		vp := m.paneViewport(i)
+		title := "New Tab"
+		if tab := m.tabByID(m.panes[i].tabID); tab != nil && tab.Title != "" {
+			title = tab.Title
+		}
+		if index := m.tabIndex(m.panes[i].tabID); index >= 0 {
+			title = fmt.Sprintf("%d: %s", index+1, title)
+		}
+
+		style := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Background(lipgloss.Color("235"))
+		if i == m.focusedPane {
+			style = style.Foreground(lipgloss.Color("255")).Background(lipgloss.Color("62"))
+		}
+		titleBar := style.Width(vp.Width).MaxWidth(vp.Width).Padding(0, 1).Render(title)
+		blocks[i] = lipgloss.JoinVertical(lipgloss.Left, titleBar, vp.View())
+	}
+
+	if m.split == splitHorizontal {
+		return lipgloss.JoinVertical(lipgloss.Left, blocks[0], blocks[1])
+	}
+	height := lipgloss.Height(blocks[0])
+	separator := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render(strings.TrimSuffix(strings.Repeat("│\n", height), "\n"))
+	return lipgloss.JoinHorizontal(lipgloss.Top, blocks[0], separator, blocks[1])
+}
+
+// Handle "op <n>": open item n in the other pane, splitting if needed
+func (m *model) handleOpenInPane(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	num, err := strconv.Atoi(args)
+	if err != nil {
+		activeTab.setError("Usage: op <link number>")
+		return nil
+	}
+	url, ok := m.numberedURL(num, activeTab)
+	if !ok {
+		activeTab.setError("Invalid link number")
+		return nil
+	}
+
+	if m.split == splitNone {
+		m.openSplit(splitVertical)
+		if m.split == splitNone {
+			return nil
+		}
+	}
+	other := 1 - m.focusedPane
+	tab := m.tabByID(m.panes[other].tabID)
+	if tab == nil || tab.ID == m.activeTabPtr().ID {
+		if len(m.tabs) >= m.config.MaxTabs {
+			m.activeTabPtr().setError(fmt.Sprintf("Max tabs limit: %d", m.config.MaxTabs))
+			return nil
+		}
+		private := m.activeTabPtr().Private
+		tab = &m.tabs[m.newTab("")]
+		tab.Private = private
+		m.panes[other].tabID = tab.ID
+	}
+
+	tab.navigateTo(url)
+	tab.ReaderMode = false
+	tab.CurrentImage = nil
+	tab.updateLoading("Opening in other pane...")
+	tab.Display = fmt.Sprintf("🔄 Loading: %s", url)
+	m.panes[other].viewport.SetContent(tab.Display)
+	m.activeTabPtr().setError("")
+	return fetchContentWithLinks(url, tab.ID)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Private tabs are drawn in this color in the tab bar and status panel
+const privateColor = lipgloss.Color("90")
+
+// Open a private tab, loading url if given. Nothing visited in it reaches
+// history, the page index or saved sessions.
+func (m *model) handlePrivateTab(url string) (tea.Model, tea.Cmd) {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	if !m.config.EnableTabs {
+		activeTab.setError("Tabs feature disabled")
+		return m, nil
+	}
+	if len(m.tabs) >= m.config.MaxTabs {
+		activeTab.setError(fmt.Sprintf("Max tabs limit: %d", m.config.MaxTabs))
+		return m, nil
+	}
+
+	index := m.newTab("")
+	m.tabs[index].Private = true
+	m.switchTab(index)
+	activeTab = m.activeTabPtr()
+	activeTab.Display = "🕶️ Private Tab\n\nPages opened here stay out of history, the page index and saved sessions. Bookmarks are only saved when you ask."
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+	}
+	if url == "" {
+		return m, nil
+	}
+	return m.handleURLOrSearch(url, activeTab)
+}
+
+// hasPrivateTabs reports whether any open tab is private
+func (m *model) hasPrivateTabs() bool {
+	for _, tab := range m.tabs {
+		if tab.Private {
+			return true
+		}
+	}
+	return false
+}
+
+// forgetPrivateTabs drops what is left of private browsing once the last
+// private tab is gone, so closed private tabs can't be reopened
+func (m *model) forgetPrivateTabs() {
+	if m.hasPrivateTabs() {
+		return
+	}
+	kept := m.closedTabs[:0]
+	for _, closed := range m.closedTabs {
+		if !closed.Tab.Private {
+			kept = append(kept, closed)
+		}
+	}
+	clear(m.closedTabs[len(kept):])
+	m.closedTabs = kept
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Commands accepted over the remote control socket, one JSON request per
+// line: {"command": "open", "args": ["https://example.com"]}
+var remoteCommands = []string{"open", "tabopen", "reload", "get-url", "get-content", "list-tabs"}
+
+// How long a connection waits for the program to answer
+const remoteTimeout = 5 * time.Second
+
+type remoteRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+type remoteResponse struct {
+	OK     bool   `json:"ok"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// remoteMsg carries a request into Update; the answer goes back on reply
+type remoteMsg struct {
+	request remoteRequest
+	reply   chan remoteResponse
+}
+
+// remoteTab is one entry of list-tabs. Private tabs are listed without
+// their title or URL.
+type remoteTab struct {
+	Index   int    `json:"index"`
+	Title   string `json:"title,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Active  bool   `json:"active"`
+	Pinned  bool   `json:"pinned,omitempty"`
+	Private bool   `json:"private,omitempty"`
+	Loading bool   `json:"loading,omitempty"`
+}
+
+// remoteSocketPath prefers the per-user runtime directory, which is cleaned
+// up on logout, over the cache directory. Either way the socket sits in a
+// directory of its own that only the user can enter.
+func remoteSocketPath(paths Paths) string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, appName, "remote.sock")
+	}
+	return filepath.Join(paths.Cache, "remote", "remote.sock")
+}
+
+// startRemoteServer listens on socketPath and forwards requests to p. It
+// returns a function that stops the server, or nil if another instance is
+// already listening.
+func startRemoteServer(socketPath string, p *tea.Program) func() {
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		log.Printf("Remote control socket %s is in use by another instance", socketPath)
+		return nil
+	}
+	// Nobody answered, so whatever is there is left over from a crash
+	os.Remove(socketPath)
+
+	// The socket is created with the umask, so other users are kept out by
+	// the directory instead of a chmod that would come too late
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		log.Printf("Error starting remote control: %v", err)
+		return nil
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		log.Printf("Error securing remote control directory: %v", err)
+		return nil
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		log.Printf("Error starting remote control: %v", err)
+		return nil
+	}
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		log.Printf("Error securing remote control socket: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("Remote control stopped: %v", err)
+				}
+				return
+			}
+			go serveRemote(conn, p)
+		}
+	}()
+
+	return func() {
+		listener.Close()
+		os.Remove(socketPath)
+	}
+}
+
+// serveRemote answers every request sent on one connection
+func serveRemote(conn net.Conn, p *tea.Program) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req remoteRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			encoder.Encode(remoteResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		reply := make(chan remoteResponse, 1)
+		p.Send(remoteMsg{request: req, reply: reply})
+		select {
+		case resp := <-reply:
+			encoder.Encode(resp)
+		case <-time.After(remoteTimeout):
+			encoder.Encode(remoteResponse{Error: "browser did not respond"})
+		}
+	}
+}
+
+// runRemote sends one command to a running instance and prints the result.
+// It returns the process exit code.
+func runRemote(socketPath string, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "usage: bubbles -remote <command> [args]\ncommands: %s\n",
+			strings.Join(remoteCommands, ", "))
+		return exitUsage
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, remoteTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bubbles: no running browser at %s: %v\n", socketPath, err)
+		return exitError
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * remoteTimeout))
+
+	if err := json.NewEncoder(conn).Encode(remoteRequest{Command: args[0], Args: args[1:]}); err != nil {
+		fmt.Fprintf(os.Stderr, "bubbles: %v\n", err)
+		return exitError
+	}
+	var resp remoteResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		fmt.Fprintf(os.Stderr, "bubbles: reading response: %v\n", err)
+		return exitError
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "bubbles: %s\n", resp.Error)
+		return exitError
+	}
+
+	switch result := resp.Result.(type) {
+	case nil:
+	case string:
+		fmt.Println(strings.TrimRight(result, "\n"))
+	default:
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+	}
+	return exitOK
+}
+
+// handleRemote runs a remote control command inside the program
+func (m *model) handleRemote(msg remoteMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	resp := remoteResponse{OK: true}
+	arg := strings.TrimSpace(strings.Join(msg.request.Args, " "))
+	activeTab := m.activeTabPtr()
+
+	switch msg.request.Command {
+	case "open":
+		if arg == "" {
+			resp = remoteResponse{Error: "usage: open <url or search>"}
+			break
+		}
+		_, cmd = m.handleURLOrSearch(arg, activeTab)
+
+	case "tabopen":
+		if arg == "" {
+			resp = remoteResponse{Error: "usage: tabopen <url or search>"}
+			break
+		}
+		if !m.config.EnableTabs || len(m.tabs) >= m.config.MaxTabs {
+			resp = remoteResponse{Error: fmt.Sprintf("can't open more tabs (max %d)", m.config.MaxTabs)}
+			break
+		}
+		m.switchTab(m.newTab(""))
+		_, cmd = m.handleURLOrSearch(arg, m.activeTabPtr())
+
+	case "reload":
+		_, cmd = m.handleReload()
+
+	case "get-url", "get-content":
+		// What happens in private tabs stays there
+		if activeTab.Private {
+			resp = remoteResponse{Error: "the active tab is private"}
+		} else if msg.request.Command == "get-url" {
+			resp.Result = activeTab.URL
+		} else {
+			resp.Result = activeTab.Markdown
+		}
+
+	case "list-tabs":
+		tabs := make([]remoteTab, len(m.tabs))
+		for i, tab := range m.tabs {
+			tabs[i] = remoteTab{
+				Index:   i + 1,
+				Active:  i == m.activeTab,
+				Pinned:  tab.Pinned,
+				Private: tab.Private,
+				Loading: tab.Status.Loading,
+			}
+			if !tab.Private {
+				tabs[i].Title = tab.Title
+				tabs[i].URL = tab.URL
+			}
+		}
+		resp.Result = tabs
+
+	default:
+		resp = remoteResponse{Error: fmt.Sprintf("unknown command %q, want one of %s",
+			msg.request.Command, strings.Join(remoteCommands, ", "))}
+	}
+
+	msg.reply <- resp
+	return m, cmd
+}
@@ -7,25 +7,44 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+const maxHistoryRows = 200
+
 // Enhanced View with status panel
 func (m *model) View() string {
 	if !m.ready {
 		return "\n  Initializing..."
 	}
 
+	viewportView := m.viewport.View()
+	if m.split != splitNone {
+		viewportView = m.renderPanes()
+	}
+	dropdown := ""
+	if len(m.suggestions) > 0 {
+		// The dropdown replaces the spacer line and pushes the viewport's
+		// bottom rows off screen instead of growing the layout
+		dropdown = m.renderSuggestions()
+		lines := strings.Split(viewportView, "\n")
+		if hidden := len(m.suggestions) - 1; hidden < len(lines) {
+			lines = lines[:len(lines)-hidden]
+		}
+		viewportView = strings.Join(lines, "\n")
+	}
+
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		m.renderTabBar(),
 		m.statusView(),
 		m.urlInput.View(),
-		"",
-		m.viewport.View(),
+		dropdown,
+		viewportView,
 		m.renderStatusPanel(),
 	)
 }
 
 func (m *model) renderImages() string {
-	if len(m.images) == 0 {
+	activeTab := m.activeTabPtr()
+	if len(activeTab.Images) == 0 {
 		return "# Images\n\nNo images found on this page."
 	}
 
@@ -33,7 +52,7 @@ func (m *model) renderImages() string {
 	content.WriteString("# Images on This Page\n\n")
 	content.WriteString("Type a number to view image details.\n\n")
 
-	for i, img := range m.images {
+	for i, img := range activeTab.Images {
 		altText := img.AltText
 		if altText == "" {
 			altText = "No description"
@@ -43,7 +62,7 @@ func (m *model) renderImages() string {
 		content.WriteString(fmt.Sprintf("URL: %s\n\n", img.URL))
 	}
 
-	content.WriteString(fmt.Sprintf("Total: %d images | Type number for details", len(m.images)))
+	content.WriteString(fmt.Sprintf("Total: %d images | Type number for details", len(activeTab.Images)))
 
 	styled, err := renderWithStyle(content.String())
 	if err != nil {
@@ -52,34 +71,51 @@ func (m *model) renderImages() string {
 	return styled
 }
 
-func (m *model) renderHistory() string {
-	activeTab := m.activeTabPtr()
-	if activeTab == nil || len(activeTab.History) == 0 {
+func (m *model) renderHistory(tab *Tab) string {
+	if len(tab.HistoryResults) == 0 {
+		if tab.HistoryQuery != "" {
+			return fmt.Sprintf("# Browser History\n\nNo history matches: **%s**", tab.HistoryQuery)
+		}
 		return "# Browser History\n\nNo history yet. Start browsing to build history!"
 	}
 	var historyContent strings.Builder
 	historyContent.WriteString("# Browser History\n\n")
+	if tab.HistoryQuery != "" {
+		historyContent.WriteString(fmt.Sprintf("Matching: **%s**\n\n", tab.HistoryQuery))
+	}
 	historyContent.WriteString(
-		"Use ←/→ or B/F to navigate, or type a number to jump to that page.\n\n",
+		"Type a number to open, `history <terms>` to search, `history delete <n>` to remove.\n\n",
 	)
-	for i, url := range activeTab.History {
-		indicator := "  "
-		if i == activeTab.CurrentPos {
-			indicator = "➤ "
+
+	shown := tab.HistoryResults
+	if len(shown) > maxHistoryRows {
+		shown = shown[:maxHistoryRows]
+	}
+	currentDay := ""
+	for i, entry := range shown {
+		if day := historyDayLabel(entry.VisitTime); day != currentDay {
+			currentDay = day
+			historyContent.WriteString(fmt.Sprintf("## %s\n\n", day))
 		}
-		displayURL := url
+		title := entry.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		displayURL := entry.URL
 		if len(displayURL) > 60 {
 			displayURL = displayURL[:57] + "..."
 		}
-		historyContent.WriteString(fmt.Sprintf("%s[%d] %s\n", indicator, i+1, displayURL))
+		historyContent.WriteString(fmt.Sprintf("[%d] %s **%s** (%d visits)\n",
+			i+1, entry.VisitTime.Format("15:04"), title, entry.VisitCount))
+		historyContent.WriteString(fmt.Sprintf("    %s\n\n", displayURL))
 	}
-	historyContent.WriteString(
-		fmt.Sprintf(
-			"\nTotal: %d pages | Current position: %d",
-			len(activeTab.History),
-			activeTab.CurrentPos+1,
-		),
-	)
+
+	footer := fmt.Sprintf("Total: %d pages", len(tab.HistoryResults))
+	if len(tab.HistoryResults) > len(shown) {
+		footer += fmt.Sprintf(" | Showing most recent %d, search to narrow down", len(shown))
+	}
+	historyContent.WriteString(footer)
+
 	styledHistory, err := renderWithStyle(historyContent.String())
 	if err != nil {
 		return historyContent.String()
@@ -87,26 +123,61 @@ func (m *model) renderHistory() string {
 	return styledHistory
 }
 
-func (m *model) renderBookmarks() string {
+func (m *model) renderBookmarks(tab *Tab, notice string) string {
 	if len(m.bookmarks) == 0 {
 		return "# Bookmarks\n\nNo bookmarks yet! Use Ctrl+D to bookmark the current page.\n\n⭐ **Tip**: Visit your favorite sites and press Ctrl+D to save them!"
 	}
 	var bookmarksContent strings.Builder
 	bookmarksContent.WriteString("# Bookmarks\n\n")
+	if notice != "" {
+		bookmarksContent.WriteString(fmt.Sprintf("✅ %s\n\n", notice))
+	}
+	if tab.BookmarkQuery != "" {
+		bookmarksContent.WriteString(fmt.Sprintf("Matching: **%s**\n\n", tab.BookmarkQuery))
+	}
 	bookmarksContent.WriteString(
 		"Type a number to open that bookmark, or Ctrl+D to bookmark current page.\n\n",
 	)
+	if len(tab.BookmarkResults) == 0 {
+		bookmarksContent.WriteString("No bookmarks match.\n\n")
+	}
+
+	// Results arrive grouped by folder, unfiled bookmarks first
+	folder := ""
+	byURL := make(map[string]int, len(m.bookmarks))
 	for i, bookmark := range m.bookmarks {
+		byURL[bookmark.URL] = i
+	}
+	for i, url := range tab.BookmarkResults {
+		index, ok := byURL[url]
+		if !ok {
+			// Removed by another instance since the list was filtered
+			continue
+		}
+		bookmark := m.bookmarks[index]
+		if bookmark.Folder != folder {
+			folder = bookmark.Folder
+			bookmarksContent.WriteString(fmt.Sprintf("## 📁 %s\n\n", folder))
+		}
+
 		displayURL := bookmark.URL
 		if len(displayURL) > 50 {
 			displayURL = displayURL[:47] + "..."
 		}
-		bookmarksContent.WriteString(fmt.Sprintf("[%d] **%s**\n", i+1, bookmark.Title))
-		bookmarksContent.WriteString(fmt.Sprintf("    %s\n\n", displayURL))
+		bookmarksContent.WriteString(fmt.Sprintf("[%d] **%s**", i+1, bookmark.Title))
+		for _, tag := range bookmark.Tags {
+			bookmarksContent.WriteString(fmt.Sprintf(" `#%s`", tag))
+		}
+		bookmarksContent.WriteString("\n")
+		bookmarksContent.WriteString(fmt.Sprintf("    %s\n", displayURL))
+		if bookmark.Description != "" {
+			bookmarksContent.WriteString(fmt.Sprintf("    *%s*\n", bookmark.Description))
+		}
+		bookmarksContent.WriteString("\n")
 	}
 	bookmarksContent.WriteString(
 		fmt.Sprintf(
-			"Total: %d bookmarks | Ctrl+D: Bookmark current | B: Show bookmarks",
+			"Total: %d bookmarks | `bookmarks #tag @folder text` to filter | Ctrl+D: Bookmark current",
 			len(m.bookmarks),
 		),
 	)
@@ -117,7 +188,8 @@ func (m *model) renderBookmarks() string {
 	return styledBookmarks
 }
 
-func (m *model) renderSearchResults(query string, results []SearchResult) string {
+func (m *model) renderSearchResults(tab *Tab) string {
+	query, results := tab.SearchQuery, tab.SearchResults
 	if len(results) == 0 {
 		return fmt.Sprintf(
 			"# Search Results\n\nNo results found for: **%s**\n\nTry a different search query.",
@@ -126,22 +198,375 @@ func (m *model) renderSearchResults(query string, results []SearchResult) string
 	}
 	var searchContent strings.Builder
 	searchContent.WriteString("# Search Results\n\n")
-	searchContent.WriteString(fmt.Sprintf("Query: **%s**\n\n", query))
-	searchContent.WriteString("Type a number to open that result.\n\n")
-	for _, result := range results {
+	searchContent.WriteString(fmt.Sprintf("Query: **%s** on %s, page %d\n\n",
+		query, tab.SearchEngine.Name, tab.SearchPage+1))
+	searchContent.WriteString("Type a number, or pick a result with ↑/↓ and press Enter.\n\n")
+	for i, result := range results {
+		if i == tab.SearchSelected {
+			// The highlighted result expands into a preview block
+			searchContent.WriteString(fmt.Sprintf("▶ [%d] **%s**\n\n", result.Number, result.Title))
+			searchContent.WriteString(fmt.Sprintf("> %s\n>\n", result.URL))
+			if result.Snippet != "" {
+				searchContent.WriteString(fmt.Sprintf("> *%s*\n>\n", result.Snippet))
+			}
+			searchContent.WriteString(fmt.Sprintf("> %s\n\n", m.resultStatus(result.URL)))
+			continue
+		}
+		snippet := result.Snippet
+		if len(snippet) > 100 {
+			snippet = snippet[:97] + "..."
+		}
 		searchContent.WriteString(fmt.Sprintf("[%d] **%s**\n", result.Number, result.Title))
 		searchContent.WriteString(fmt.Sprintf("    %s\n", result.URL))
-		if result.Snippet != "" {
-			searchContent.WriteString(fmt.Sprintf("    *%s*\n", result.Snippet))
+		if snippet != "" {
+			searchContent.WriteString(fmt.Sprintf("    *%s*\n", snippet))
 		}
 		searchContent.WriteString("\n")
 	}
-	searchContent.WriteString(
-		fmt.Sprintf("Found %d results | Type number to open | Ctrl+S: New search", len(results)),
-	)
+
+	pages := "next/prev or Alt+N/Alt+B: Change page"
+	if tab.SearchPage == len(tab.SearchPages)-1 && tab.SearchPages[tab.SearchPage].Next == nil {
+		pages = "Last page"
+	}
+	searchContent.WriteString(fmt.Sprintf("Page %d | %d results | %s | Ctrl+S: New search",
+		tab.SearchPage+1, len(results), pages))
 	styledSearch, err := renderWithStyle(searchContent.String())
 	if err != nil {
 		return searchContent.String()
 	}
 	return styledSearch
 }
+
+func (m *model) renderEngines() string {
+	var engineContent strings.Builder
+	engineContent.WriteString("# Search Engines\n\n")
+	engineContent.WriteString("Start a search with a keyword (`gh bubbletea`) or add a bang anywhere (`golang context !g`).\n\n")
+	defaultEngine := m.defaultEngine()
+	for _, engine := range m.searchEngines() {
+		line := fmt.Sprintf("- `%s` **%s**", engine.Keyword, engine.Name)
+		if engine.Name == defaultEngine.Name {
+			line += " (default)"
+		}
+		engineContent.WriteString(line + "\n")
+		engineContent.WriteString(fmt.Sprintf("    %s\n", engine.URL))
+	}
+	engineContent.WriteString("\nAdd engines with `search_engines` in config.json and pick the default with `default_engine`.")
+
+	styledEngines, err := renderWithStyle(engineContent.String())
+	if err != nil {
+		return engineContent.String()
+	}
+	return styledEngines
+}
+
+func (m *model) renderFindResults(tab *Tab) string {
+	var findContent strings.Builder
+	findContent.WriteString("# Find in History\n\n")
+	findContent.WriteString(fmt.Sprintf("Pages you visited mentioning: **%s**\n\n", tab.FindQuery))
+	if len(tab.FindResults) == 0 {
+		findContent.WriteString("No visited pages match. Pages are indexed as you browse.")
+	} else {
+		findContent.WriteString("Type a number to open the live page, or `cached <n>` for the stored copy.\n\n")
+	}
+	for i, hit := range tab.FindResults {
+		title := hit.Page.Title
+		if title == "" {
+			title = stripScheme(hit.Page.URL)
+		}
+		findContent.WriteString(fmt.Sprintf("[%d] **%s** (%s)\n", i+1, title, hit.Page.Fetched.Format("Jan 2")))
+		findContent.WriteString(fmt.Sprintf("    %s\n", hit.Page.URL))
+		if hit.Snippet != "" {
+			findContent.WriteString(fmt.Sprintf("    %s\n", hit.Snippet))
+		}
+		findContent.WriteString("\n")
+	}
+
+	styledFind, err := renderWithStyle(findContent.String())
+	if err != nil {
+		return findContent.String()
+	}
+	return styledFind
+}
+
+func (m *model) renderTOC() string {
+	activeTab := m.activeTabPtr()
+	if activeTab == nil {
+		return ""
+	}
+	var tocContent strings.Builder
+	tocContent.WriteString("# Table of Contents\n\n")
+	tocContent.WriteString("Type a number to jump to that heading.\n\n")
+
+	toc := headings(activeTab.Anchors)
+	minLevel := 6
+	for _, heading := range toc {
+		minLevel = min(minLevel, heading.Level)
+	}
+	for i, heading := range toc {
+		indent := strings.Repeat("  ", heading.Level-minLevel)
+		text := heading.Text
+		if len(text) > 70 {
+			text = text[:67] + "..."
+		}
+		tocContent.WriteString(fmt.Sprintf("%s- [%d] %s\n", indent, i+1, text))
+	}
+	tocContent.WriteString(fmt.Sprintf("\nTotal: %d headings | [[ / ]] to move between headings", len(toc)))
+
+	styledTOC, err := renderWithStyle(tocContent.String())
+	if err != nil {
+		return tocContent.String()
+	}
+	return styledTOC
+}
+
+func (m *model) renderClosedTabs() string {
+	if len(m.closedTabs) == 0 {
+		return "# Recently Closed Tabs\n\nNo closed tabs yet."
+	}
+	var closedContent strings.Builder
+	closedContent.WriteString("# Recently Closed Tabs\n\n")
+	closedContent.WriteString("Type a number to reopen that tab, or `undo` for the latest.\n\n")
+	for i := len(m.closedTabs) - 1; i >= 0; i-- {
+		closed := m.closedTabs[i]
+		title := closed.Tab.Title
+		if title == "" {
+			title = "New Tab"
+		}
+		displayURL := closed.Tab.URL
+		if len(displayURL) > 60 {
+			displayURL = displayURL[:57] + "..."
+		}
+		closedContent.WriteString(fmt.Sprintf("[%d] **%s** (closed %s, was tab %d)\n",
+			len(m.closedTabs)-i, title, closed.ClosedAt.Format("15:04"), closed.Index+1))
+		closedContent.WriteString(fmt.Sprintf("    %s\n\n", displayURL))
+	}
+	closedContent.WriteString(fmt.Sprintf("Total: %d closed tabs", len(m.closedTabs)))
+
+	styledClosed, err := renderWithStyle(closedContent.String())
+	if err != nil {
+		return closedContent.String()
+	}
+	return styledClosed
+}
+
+func (m *model) renderTabList(tab *Tab, query string) string {
+	var tabContent strings.Builder
+	tabContent.WriteString("# Open Tabs\n\n")
+	if query != "" {
+		tabContent.WriteString(fmt.Sprintf("Matching: **%s**\n\n", query))
+	}
+	if len(tab.TabResults) == 0 {
+		tabContent.WriteString("No tabs match.")
+	} else {
+		tabContent.WriteString("Type a number to switch to that tab, or `tabs <query>` to filter.\n\n")
+	}
+	for i, id := range tab.TabResults {
+		index := m.tabIndex(id)
+		if index < 0 {
+			continue
+		}
+		listed := m.tabs[index]
+		title := listed.Title
+		if title == "" {
+			title = "New Tab"
+		}
+		marker := ""
+		if listed.Pinned {
+			marker += " 📌"
+		}
+		if index == m.activeTab {
+			marker += " (current)"
+		}
+		displayURL := listed.URL
+		if len(displayURL) > 60 {
+			displayURL = displayURL[:57] + "..."
+		}
+		tabContent.WriteString(fmt.Sprintf("[%d] **%s**%s (tab %d)\n", i+1, title, marker, index+1))
+		tabContent.WriteString(fmt.Sprintf("    %s\n\n", displayURL))
+	}
+
+	styledTabs, err := renderWithStyle(tabContent.String())
+	if err != nil {
+		return tabContent.String()
+	}
+	return styledTabs
+}
+
+func (m *model) renderRewrites(note string) string {
+	var rewriteContent strings.Builder
+	rewriteContent.WriteString("# URL Rewrites\n\n")
+	if note != "" {
+		rewriteContent.WriteString(fmt.Sprintf("*%s*\n\n", note))
+	}
+	if !rewriter.Enabled {
+		rewriteContent.WriteString("Rewriting is **off**; `rewrites on` turns it back on.\n\n")
+	}
+	rewriteContent.WriteString("Rules run in order before every page is fetched. `rewrites toggle <n>` turns one on or off.\n\n")
+	for i, rule := range rewriter.Rules {
+		state := "off"
+		if rule.Enabled {
+			state = "on"
+		}
+		from := rule.Match
+		if rule.Host != "" {
+			from = rule.Host + " " + from
+		}
+		rewriteContent.WriteString(fmt.Sprintf("[%d] **%s** (%s)\n", i+1, rule.Name, state))
+		rewriteContent.WriteString(fmt.Sprintf("    `%s` → `%s`\n\n", strings.TrimSpace(from), rule.Replace))
+	}
+	rewriteContent.WriteString("Add rules with `rewrite_rules` in config.json.")
+
+	styledRewrites, err := renderWithStyle(rewriteContent.String())
+	if err != nil {
+		return rewriteContent.String()
+	}
+	return styledRewrites
+}
+
+func (m *model) renderFilters(note string) string {
+	var filterContent strings.Builder
+	filterContent.WriteString("# Content Filters\n\n")
+	if note != "" {
+		filterContent.WriteString(fmt.Sprintf("*%s*\n\n", note))
+	}
+	if contentFilter == nil {
+		filterContent.WriteString("No filter lists loaded. Add Adblock Plus or EasyList files under `filter_lists` in config.json.")
+	} else {
+		filterContent.WriteString("## Lists\n\n")
+		for _, list := range contentFilter.lists {
+			if list.Err != nil {
+				filterContent.WriteString(fmt.Sprintf("- ❌ %s: %v\n", list.Path, list.Err))
+				continue
+			}
+			filterContent.WriteString(fmt.Sprintf("- %s: %d network rules, %d hiding rules\n",
+				list.Path, list.Network, list.Hiding))
+		}
+		filterContent.WriteString("\n## Whitelist\n\n")
+		filterContent.WriteString("`filters allow` turns filtering off for the current site, `filters block` turns it back on.\n\n")
+		if len(contentFilter.configAllowed)+len(contentFilter.allowed) == 0 {
+			filterContent.WriteString("Every site is filtered.\n")
+		}
+		for _, host := range contentFilter.configAllowed {
+			filterContent.WriteString(fmt.Sprintf("- %s (config.json)\n", host))
+		}
+		for _, host := range contentFilter.allowed {
+			filterContent.WriteString(fmt.Sprintf("- %s\n", host))
+		}
+	}
+
+	styledFilters, err := renderWithStyle(filterContent.String())
+	if err != nil {
+		return filterContent.String()
+	}
+	return styledFilters
+}
+
+func (m *model) renderFeeds(tab *Tab, note string) string {
+	var feedContent strings.Builder
+	feedContent.WriteString("# Feeds\n\n")
+	if note != "" {
+		feedContent.WriteString(fmt.Sprintf("*%s*\n\n", note))
+	}
+	if len(m.feeds) == 0 {
+		feedContent.WriteString("No subscriptions yet. Pages with a feed show 📡 in the status panel; `subscribe` adds it, or `subscribe <feed url>` for any RSS or Atom feed.")
+	} else {
+		feedContent.WriteString(fmt.Sprintf("## Unread (%d)\n\n", len(tab.FeedResults)))
+		if len(tab.FeedResults) == 0 {
+			feedContent.WriteString("All caught up.\n\n")
+		} else {
+			feedContent.WriteString("Type a number to read an item in reader mode. `feeds read` marks everything read.\n\n")
+		}
+		for i, ref := range tab.FeedResults {
+			feed, item := m.feedItem(ref)
+			if item == nil {
+				continue
+			}
+			details := []string{feed.Title}
+			if !item.Published.IsZero() {
+				details = append(details, item.Published.Local().Format("Jan 2"))
+			}
+			feedContent.WriteString(fmt.Sprintf("[%d] **%s**\n", i+1, item.Title))
+			feedContent.WriteString(fmt.Sprintf("    %s\n\n", joinDetails(details...)))
+		}
+
+		feedContent.WriteString("## Subscriptions\n\n")
+		feedContent.WriteString("`feeds refresh` checks them all now; `feeds remove <n>` unsubscribes.\n\n")
+		for i, feed := range m.feeds {
+			unread := 0
+			for _, item := range feed.Items {
+				if !item.Read {
+					unread++
+				}
+			}
+			feedContent.WriteString(fmt.Sprintf("(%d) **%s**, %d unread\n", i+1, feed.Title, unread))
+			feedContent.WriteString(fmt.Sprintf("    %s\n", feed.URL))
+			if feed.Error != "" {
+				feedContent.WriteString(fmt.Sprintf("    ❌ %s\n", feed.Error))
+			} else if !feed.Checked.IsZero() {
+				feedContent.WriteString(fmt.Sprintf("    checked %s\n", feed.Checked.Local().Format("Jan 2 15:04")))
+			}
+			feedContent.WriteString("\n")
+		}
+	}
+
+	styledFeeds, err := renderWithStyle(feedContent.String())
+	if err != nil {
+		return feedContent.String()
+	}
+	return styledFeeds
+}
+
+func (m *model) renderLater(tab *Tab, note string) string {
+	var laterContent strings.Builder
+	if tab.LaterArchived {
+		laterContent.WriteString("# Read Later: Archive\n\n")
+	} else {
+		laterContent.WriteString("# Read Later\n\n")
+	}
+	if note != "" {
+		laterContent.WriteString(fmt.Sprintf("*%s*\n\n", note))
+	}
+	switch {
+	case len(tab.LaterResults) == 0 && tab.LaterArchived:
+		laterContent.WriteString("Nothing archived. `later archive <n>` moves an item here.")
+	case len(tab.LaterResults) == 0:
+		laterContent.WriteString("Nothing saved. `later` saves the page you're reading, `later images` its images too.")
+	default:
+		laterContent.WriteString("Type a number to open the saved copy, even offline. `later read <n>`, `unread`, `archive` and `delete` manage items.\n\n")
+	}
+
+	section := ""
+	for i, id := range tab.LaterResults {
+		index := laterIndex(m.later, id)
+		if index < 0 {
+			// Deleted by another instance since the list was shown
+			continue
+		}
+		item := m.later[index]
+		if !tab.LaterArchived {
+			heading := "Unread"
+			if item.Read {
+				heading = "Read"
+			}
+			if heading != section {
+				laterContent.WriteString(fmt.Sprintf("## %s\n\n", heading))
+				section = heading
+			}
+		}
+		details := []string{stripScheme(item.URL), readingTime(item.Words), "saved " + item.Saved.Local().Format("Jan 2")}
+		if item.Images > 0 {
+			details = append(details, fmt.Sprintf("%d images", item.Images))
+		}
+		laterContent.WriteString(fmt.Sprintf("[%d] **%s**\n", i+1, item.Title))
+		laterContent.WriteString(fmt.Sprintf("    %s\n\n", joinDetails(details...)))
+	}
+	if !tab.LaterArchived {
+		laterContent.WriteString("`later list archived` shows the archive.")
+	}
+
+	styledLater, err := renderWithStyle(laterContent.String())
+	if err != nil {
+		return laterContent.String()
+	}
+	return styledLater
+}
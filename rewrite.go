@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// RewriteRule sends a URL somewhere else before it is fetched, such as a
+// lighter front-end for the same site. Host rules swap the host; Match
+// rules replace a regular expression over the whole URL. A rule with both
+// only rewrites URLs on that host.
+type RewriteRule struct {
+	Name    string `json:"name"`
+	Host    string `json:"host,omitempty"`  // "*.example.com" also matches subdomains
+	Match   string `json:"match,omitempty"` // regular expression
+	Replace string `json:"replace"`         // the new host, or a template using ${1} for Match rules
+	Enabled bool   `json:"enabled"`
+
+	pattern *regexp.Regexp
+}
+
+// Rules shipped with the browser. They stay off until enabled in
+// config.json or with "rewrites toggle".
+var builtinRewrites = []RewriteRule{
+	{Name: "old-reddit", Host: "*.reddit.com", Replace: "old.reddit.com"},
+	{
+		Name:    "wikipedia-mobile",
+		Match:   `^https?://([a-z-]+)\.wikipedia\.org/`,
+		Replace: "https://${1}.m.wikipedia.org/",
+	},
+}
+
+// Rewriter applies the rules in order to every URL the browser fetches
+type Rewriter struct {
+	Enabled bool
+	Rules   []RewriteRule
+}
+
+// The rules used by every fetch, set up from the config at startup
+var rewriter = newRewriter(nil, true)
+
+// newRewriter adds the configured rules to the built-in ones. A configured
+// rule replaces the built-in rule of the same name; one with only a name
+// and "enabled" just switches it on or off.
+func newRewriter(configured []RewriteRule, enabled bool) *Rewriter {
+	r := &Rewriter{Enabled: enabled, Rules: append([]RewriteRule(nil), builtinRewrites...)}
+	for _, rule := range configured {
+		i := r.index(rule.Name)
+		switch {
+		case i >= 0 && rule.Host == "" && rule.Match == "":
+			r.Rules[i].Enabled = rule.Enabled
+		case i >= 0:
+			r.Rules[i] = rule
+		default:
+			r.Rules = append(r.Rules, rule)
+		}
+	}
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if rule.Match == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(rule.Match)
+		if err != nil {
+			log.Printf("Disabling rewrite rule %q: %v", rule.Name, err)
+			rule.Enabled = false
+			continue
+		}
+		rule.pattern = pattern
+	}
+	return r
+}
+
+func (r *Rewriter) index(name string) int {
+	for i, rule := range r.Rules {
+		if name != "" && strings.EqualFold(rule.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Rewrite runs rawURL through every enabled rule and returns the result
+// with the names of the rules that changed it
+func (r *Rewriter) Rewrite(rawURL string) (string, []string) {
+	if !r.Enabled {
+		return rawURL, nil
+	}
+	var applied []string
+	for _, rule := range r.Rules {
+		if !rule.Enabled {
+			continue
+		}
+		if rewritten := rule.apply(rawURL); rewritten != rawURL {
+			rawURL = rewritten
+			applied = append(applied, rule.Name)
+		}
+	}
+	return rawURL, applied
+}
+
+// Toggle flips the rule with the given number or name
+func (r *Rewriter) Toggle(key string) (*RewriteRule, bool) {
+	i := r.index(key)
+	if num, err := strconv.Atoi(key); err == nil {
+		i = num - 1
+	}
+	if i < 0 || i >= len(r.Rules) || (r.Rules[i].Match != "" && r.Rules[i].pattern == nil) {
+		return nil, false
+	}
+	r.Rules[i].Enabled = !r.Rules[i].Enabled
+	return &r.Rules[i], true
+}
+
+func (rule RewriteRule) apply(rawURL string) string {
+	if rule.Host != "" && !matchHost(rawURL, []string{rule.Host}) {
+		return rawURL
+	}
+	if rule.Match != "" {
+		if rule.pattern == nil {
+			return rawURL
+		}
+		return rule.pattern.ReplaceAllString(rawURL, rule.Replace)
+	}
+	parsed, err := url.Parse(rawURL)
+	if rule.Host == "" || rule.Replace == "" || err != nil {
+		return rawURL
+	}
+	parsed.Host = rule.Replace
+	return parsed.String()
+}
+
+// rewrittenError names the address actually fetched when it failed
+func rewrittenError(err error, fetchURL string, rules []string) error {
+	if len(rules) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s (rewritten by %s): %w", fetchURL, strings.Join(rules, ", "), err)
+}
+
+// Handle "rewrites", "rewrites on|off" and "rewrites toggle <n|name>"
+func (m *model) handleRewritesCommand(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	verb, rest, _ := strings.Cut(args, " ")
+	note := ""
+
+	switch verb {
+	case "":
+	case "on", "off":
+		rewriter.Enabled = verb == "on"
+		note = "URL rewriting turned " + verb
+	case "toggle":
+		rule, ok := rewriter.Toggle(strings.TrimSpace(rest))
+		if !ok {
+			activeTab.setError("Usage: rewrites toggle <rule number or name>")
+			return nil
+		}
+		state := "off"
+		if rule.Enabled {
+			state = "on"
+		}
+		note = fmt.Sprintf("Rule %s turned %s", rule.Name, state)
+	default:
+		activeTab.setError("Usage: rewrites [on|off|toggle <n>]")
+		return nil
+	}
+
+	activeTab.Display = m.renderRewrites(note)
+	activeTab.clearOverlays()
+	activeTab.ReaderMode = false
+	activeTab.setError("")
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SearchEngine is a search provider picked by keyword or !bang
+type SearchEngine struct {
+	Name    string `json:"name"`
+	Keyword string `json:"keyword"`
+	URL     string `json:"url"` // %s is replaced with the escaped query
+
+	// Offset parameter for engines without a next page form or link, bumped
+	// by PageSize for each page. PageStart is added for engines that count
+	// results from 1.
+	PageParam string `json:"page_param,omitempty"`
+	PageSize  int    `json:"page_size,omitempty"`
+	PageStart int    `json:"page_start,omitempty"`
+
+	// Engines without selectors open their results page like any other page
+	Selectors *ResultSelectors `json:"selectors,omitempty"`
+}
+
+// ResultSelectors are the CSS selectors used to scrape a results page
+type ResultSelectors struct {
+	Result  string `json:"result"`
+	Title   string `json:"title"`
+	Link    string `json:"link,omitempty"` // defaults to the title element
+	Snippet string `json:"snippet,omitempty"`
+
+	// Form or link leading to the next page of results
+	NextPage string `json:"next_page,omitempty"`
+}
+
+// searchRequest fetches one page of results
+type searchRequest struct {
+	URL  string
+	Form url.Values // POSTed when set
+}
+
+// searchPage is one fetched page of results
+type searchPage struct {
+	Results []SearchResult
+	Next    *searchRequest // nil on the last page
+}
+
+// Engines available without any configuration
+var builtinEngines = []SearchEngine{
+	{
+		Name:    "DuckDuckGo",
+		Keyword: "ddg",
+		URL:     "https://html.duckduckgo.com/html/?q=%s",
+		Selectors: &ResultSelectors{
+			Result:   ".result",
+			Title:    ".result__a",
+			Snippet:  ".result__snippet",
+			NextPage: `.nav-link form:has(input[value="Next"])`,
+		},
+	},
+	{
+		Name:      "Bing",
+		Keyword:   "b",
+		URL:       "https://www.bing.com/search?q=%s",
+		PageParam: "first",
+		PageSize:  10,
+		PageStart: 1,
+		Selectors: &ResultSelectors{
+			Result:  "li.b_algo",
+			Title:   "h2 a",
+			Snippet: ".b_caption p",
+		},
+	},
+	{Name: "Google", Keyword: "g", URL: "https://www.google.com/search?q=%s"},
+	{Name: "Wikipedia", Keyword: "w", URL: "https://en.wikipedia.org/w/index.php?search=%s"},
+	{Name: "GitHub", Keyword: "gh", URL: "https://github.com/search?q=%s"},
+}
+
+const maxSearchResults = 10
+
+// searchURL fills the engine's template with query
+func (e SearchEngine) searchURL(query string) string {
+	return strings.ReplaceAll(e.URL, "%s", url.QueryEscape(query))
+}
+
+// searchEngines returns the built-in engines with configured ones added.
+// A configured engine replaces a built-in one with the same keyword.
+func (m *model) searchEngines() []SearchEngine {
+	engines := append([]SearchEngine{}, m.config.SearchEngines...)
+	for _, builtin := range builtinEngines {
+		if m.engineByKeyword(engines, builtin.Keyword) == nil {
+			engines = append(engines, builtin)
+		}
+	}
+	return engines
+}
+
+// engineByKeyword finds an engine by keyword or name, ignoring case
+func (m *model) engineByKeyword(engines []SearchEngine, keyword string) *SearchEngine {
+	if keyword == "" {
+		return nil
+	}
+	for i := range engines {
+		if strings.EqualFold(engines[i].Keyword, keyword) || strings.EqualFold(engines[i].Name, keyword) {
+			return &engines[i]
+		}
+	}
+	return nil
+}
+
+// defaultEngine returns the configured default, falling back to DuckDuckGo
+func (m *model) defaultEngine() SearchEngine {
+	engines := m.searchEngines()
+	if engine := m.engineByKeyword(engines, m.config.DefaultEngine); engine != nil {
+		return *engine
+	}
+	return builtinEngines[0]
+}
+
+// explicitEngine picks an engine named in input, either as a leading keyword
+// ("gh bubbletea") or a bang anywhere ("golang context !g"). It returns the
+// query with the keyword removed.
+func (m *model) explicitEngine(input string) (SearchEngine, string, bool) {
+	engines := m.searchEngines()
+	fields := strings.Fields(input)
+	for i, field := range fields {
+		bang, ok := strings.CutPrefix(field, "!")
+		if !ok || bang == "" {
+			continue
+		}
+		if engine := m.engineByKeyword(engines, bang); engine != nil {
+			rest := append(append([]string{}, fields[:i]...), fields[i+1:]...)
+			return *engine, strings.Join(rest, " "), true
+		}
+	}
+	if len(fields) > 1 {
+		for _, engine := range engines {
+			if engine.Keyword != "" && strings.EqualFold(fields[0], engine.Keyword) {
+				return engine, strings.Join(fields[1:], " "), true
+			}
+		}
+	}
+	return SearchEngine{}, "", false
+}
+
+func performSearch(engine SearchEngine, query string, tabID int) tea.Cmd {
+	return fetchSearchPage(engine, query, searchRequest{URL: engine.searchURL(query)}, 0, tabID)
+}
+
+// fetchSearchPage fetches and scrapes page (0-based) of query's results
+func fetchSearchPage(engine SearchEngine, query string, req searchRequest, page, tabID int) tea.Cmd {
+	return func() tea.Msg {
+		doc, err := req.fetch()
+		if err != nil {
+			return errorMsg{err: err, tabID: tabID}
+		}
+		results := scrapeResults(doc, engine.Selectors, req.URL)
+		var next *searchRequest
+		if len(results) > 0 {
+			next = nextSearchRequest(doc, engine, query, req.URL, page)
+		} else if page == 0 {
+			results = append(results, SearchResult{
+				Number:  1,
+				Title:   fmt.Sprintf("Search for: %s", query),
+				URL:     req.URL,
+				Snippet: fmt.Sprintf("Open the %s results page", engine.Name),
+			})
+		}
+		return searchResultsMsg{
+			query:   query,
+			engine:  engine,
+			page:    page,
+			results: results,
+			next:    next,
+			tabID:   tabID,
+		}
+	}
+}
+
+func (r searchRequest) fetch() (*goquery.Document, error) {
+	if r.Form == nil {
+		return fetchHTML(r.URL)
+	}
+	req, err := http.NewRequest("POST", r.URL, strings.NewReader(r.Form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return fetchDocument(req)
+}
+
+// nextSearchRequest finds the way to the page after page: the engine's
+// pagination form or link if it has one, otherwise its offset parameter
+func nextSearchRequest(doc *goquery.Document, engine SearchEngine, query, pageURL string, page int) *searchRequest {
+	if engine.Selectors.NextPage != "" {
+		next := doc.Find(engine.Selectors.NextPage).First()
+		if next.Is("form") {
+			action, _ := next.Attr("action")
+			form := url.Values{}
+			next.Find("input[name]").Each(func(i int, input *goquery.Selection) {
+				name, _ := input.Attr("name")
+				value, _ := input.Attr("value")
+				form.Add(name, value)
+			})
+			req := &searchRequest{URL: resolveURL(pageURL, action)}
+			if method, _ := next.Attr("method"); strings.EqualFold(method, "post") {
+				req.Form = form
+			} else {
+				req.URL = withQuery(req.URL, form)
+			}
+			return req
+		}
+		if href, ok := next.Attr("href"); ok {
+			return &searchRequest{URL: resolveURL(pageURL, href)}
+		}
+	}
+	if engine.PageParam != "" {
+		size := engine.PageSize
+		if size <= 0 {
+			size = maxSearchResults
+		}
+		params := url.Values{engine.PageParam: {strconv.Itoa((page+1)*size + engine.PageStart)}}
+		return &searchRequest{URL: withQuery(engine.searchURL(query), params)}
+	}
+	return nil
+}
+
+// withQuery sets params on rawURL's query string
+func withQuery(rawURL string, params url.Values) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// scrapeResults pulls up to maxSearchResults results out of a results page
+func scrapeResults(doc *goquery.Document, sel *ResultSelectors, pageURL string) []SearchResult {
+	var results []SearchResult
+	doc.Find(sel.Result).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		titleElem := s.Find(sel.Title).First()
+		title := strings.TrimSpace(titleElem.Text())
+		linkElem := titleElem
+		if sel.Link != "" {
+			linkElem = s.Find(sel.Link).First()
+		}
+		link, _ := linkElem.Attr("href")
+		if title == "" || link == "" {
+			return true
+		}
+		var snippet string
+		if sel.Snippet != "" {
+			snippet = strings.TrimSpace(s.Find(sel.Snippet).First().Text())
+		}
+		results = append(results, SearchResult{
+			Number:  len(results) + 1,
+			Title:   title,
+			URL:     cleanLink(unwrapRedirect(resolveURL(pageURL, link))),
+			Snippet: snippet,
+		})
+		return len(results) < maxSearchResults
+	})
+	return results
+}
+
+// Lines below the highlighted result's title taken up by its preview
+const previewHeight = 7
+
+// showSearchPage displays an already fetched page of results
+func (m *model) showSearchPage(tab *Tab, page int) {
+	tab.SearchPage = page
+	tab.SearchResults = tab.SearchPages[page].Results
+	tab.SearchSelected = -1
+	tab.clearOverlays()
+	tab.ShowSearch = true
+	tab.CurrentImage = nil
+	tab.Display = m.renderSearchResults(tab)
+	tab.DisplayOffset = 0
+	if tab == m.activeTabPtr() && m.ready {
+		m.viewport.SetContent(tab.Display)
+		m.viewport.GotoTop()
+	} else if vp := m.unfocusedPaneFor(tab); vp != nil {
+		vp.SetContent(tab.Display)
+		vp.GotoTop()
+	}
+}
+
+// searchPageCmd moves dir pages through the results, fetching the next
+// page only when it hasn't been seen yet
+func (m *model) searchPageCmd(dir int) tea.Cmd {
+	tab := m.activeTabPtr()
+	if tab == nil {
+		return nil
+	}
+	if len(tab.SearchPages) == 0 {
+		tab.setError("No search results to page through")
+		return nil
+	}
+	page := tab.SearchPage + dir
+	if page < 0 {
+		tab.setError("Already on the first page")
+		return nil
+	}
+	if page < len(tab.SearchPages) {
+		tab.setError("")
+		m.showSearchPage(tab, page)
+		return nil
+	}
+	next := tab.SearchPages[tab.SearchPage].Next
+	if next == nil {
+		tab.setError("No more results")
+		return nil
+	}
+	tab.updateLoading(fmt.Sprintf("Loading page %d...", page+1))
+	return fetchSearchPage(tab.SearchEngine, tab.SearchQuery, *next, page, tab.ID)
+}
+
+func (m *model) handleSearchPage(dir int) (tea.Model, tea.Cmd) {
+	return m, m.searchPageCmd(dir)
+}
+
+// canReturnToSearch reports whether Escape should go back to the results
+// the current page was opened from
+func (m *model) canReturnToSearch(tab *Tab) bool {
+	if tab.searchReturn == "" || len(tab.SearchPages) == 0 ||
+		tab.ShowSearch || tab.ShowHistory || tab.ShowBookmarks || tab.ShowClosed || tab.ShowTabs {
+		return false
+	}
+	entry := tab.currentEntry()
+	return entry != nil && entry.URL == tab.searchReturn
+}
+
+// handleSearchKey moves the highlighted result with the arrow keys while the
+// search view is open and the URL bar is empty. Returns true if consumed.
+func (m *model) handleSearchKey(msg tea.KeyMsg) bool {
+	tab := m.activeTabPtr()
+	if tab == nil || !tab.ShowSearch || m.urlInput.Value() != "" || len(tab.SearchResults) == 0 {
+		return false
+	}
+	var dir int
+	switch msg.String() {
+	case "down":
+		dir = 1
+	case "up":
+		dir = -1
+	default:
+		return false
+	}
+
+	count := len(tab.SearchResults)
+	switch {
+	case tab.SearchSelected < 0 && dir > 0:
+		tab.SearchSelected = 0
+	case tab.SearchSelected < 0:
+		tab.SearchSelected = count - 1
+	default:
+		tab.SearchSelected = (tab.SearchSelected + dir + count) % count
+	}
+	tab.Display = m.renderSearchResults(tab)
+	if !m.ready {
+		return true
+	}
+
+	// Keep the highlighted result and its preview on screen
+	offset := m.viewport.YOffset
+	m.viewport.SetContent(tab.Display)
+	for i, line := range strings.Split(tab.Display, "\n") {
+		if strings.Contains(line, "▶") {
+			if i < offset || i+previewHeight >= offset+m.viewport.Height {
+				offset = max(i-1, 0)
+			}
+			break
+		}
+	}
+	m.viewport.SetYOffset(offset)
+	return true
+}
+
+// resultStatus summarises what we already know about a result's page
+func (m *model) resultStatus(resultURL string) string {
+	parts := []string{"🌐 " + resultDomain(resultURL)}
+	if entry, ok := m.history.entries[resultURL]; ok {
+		parts = append(parts, fmt.Sprintf("🕘 visited %d×, last %s",
+			entry.VisitCount, entry.VisitTime.Format("Jan 2")))
+	} else {
+		parts = append(parts, "not visited")
+	}
+	if m.isBookmarked(resultURL) {
+		parts = append(parts, "⭐ bookmarked")
+	}
+	if fetched, ok := m.pageIndex.Cached(resultURL); ok && m.config.EnablePageIndex {
+		parts = append(parts, "📦 cached "+fetched.Format("Jan 2"))
+	}
+	for i, tab := range m.tabs {
+		if tab.URL == resultURL {
+			parts = append(parts, fmt.Sprintf("🗂️ open in tab %d", i+1))
+			break
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// resultDomain returns the host of a result URL without www.
+func resultDomain(resultURL string) string {
+	parsed, err := url.Parse(resultURL)
+	if err != nil || parsed.Host == "" {
+		return resultURL
+	}
+	return strings.TrimPrefix(parsed.Hostname(), "www.")
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	sessionFile = "session.json"
+	sessionDir  = "sessions"
+)
+
+// SessionTab is the persisted form of a Tab
+type SessionTab struct {
+	Title        string        `json:"title"`
+	URL          string        `json:"url"`
+	History      []HistoryItem `json:"history"`
+	CurrentPos   int           `json:"current_pos"`
+	ReaderMode   bool          `json:"reader_mode"`
+	ScrollOffset int           `json:"scroll_offset"`
+	Pinned       bool          `json:"pinned,omitempty"`
+}
+
+// Session is a snapshot of every open tab
+type Session struct {
+	SavedAt   time.Time    `json:"saved_at"`
+	ActiveTab int          `json:"active_tab"`
+	Tabs      []SessionTab `json:"tabs"`
+}
+
+type sessionTickMsg time.Time
+
+// sessionPath maps a session name to its file; "" is the automatic session
+func (m *model) sessionPath(name string) string {
+	if name == "" {
+		return m.paths.data(sessionFile)
+	}
+	return m.paths.data(filepath.Join(sessionDir, filepath.Base(name)+".json"))
+}
+
+// snapshotSession captures the current tabs, leaving out private ones
+func (m *model) snapshotSession() Session {
+	session := Session{
+		SavedAt: time.Now(),
+	}
+	for i, tab := range m.tabs {
+		if tab.Private {
+			continue
+		}
+		if i <= m.activeTab {
+			session.ActiveTab = len(session.Tabs)
+		}
+		session.Tabs = append(session.Tabs, SessionTab{
+			Title:        tab.Title,
+			URL:          tab.URL,
+			History:      tab.History,
+			CurrentPos:   tab.CurrentPos,
+			ReaderMode:   tab.ReaderMode,
+			ScrollOffset: tab.ScrollOffset,
+			Pinned:       tab.Pinned,
+		})
+	}
+	return session
+}
+
+// saveSession writes the session and returns how many tabs went into it
+func (m *model) saveSession(name string) (int, error) {
+	session := m.snapshotSession()
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return len(session.Tabs), writeFileAtomic(m.sessionPath(name), data)
+}
+
+func (m *model) loadSession(name string) (Session, error) {
+	var session Session
+	data, err := os.ReadFile(m.sessionPath(name))
+	if err != nil {
+		return session, err
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return session, err
+	}
+	return session, nil
+}
+
+// listSessions returns the names of all saved sessions
+func (m *model) listSessions() []string {
+	files, err := filepath.Glob(m.paths.data(filepath.Join(sessionDir, "*.json")))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// restoreSession replaces all tabs with the saved ones. Tabs are fetched
+// lazily; the returned command loads the active tab.
+func (m *model) restoreSession(session Session) tea.Cmd {
+	var tabs []Tab
+	for _, saved := range session.Tabs {
+		if saved.CurrentPos < 0 || saved.CurrentPos >= len(saved.History) {
+			continue
+		}
+		tabs = append(tabs, Tab{
+			ID:            m.nextTabID + len(tabs),
+			Title:         saved.Title,
+			URL:           saved.History[saved.CurrentPos].URL,
+			Links:         []Link{},
+			Images:        []ImageInfo{},
+			ReaderMode:    saved.ReaderMode,
+			History:       saved.History,
+			CurrentPos:    saved.CurrentPos,
+			ScrollOffset:  saved.ScrollOffset,
+			Pinned:        saved.Pinned,
+			restoreScroll: true,
+		})
+	}
+	if len(tabs) == 0 {
+		return nil
+	}
+
+	m.tabs = tabs
+	m.nextTabID += len(tabs)
+	m.forgetPrivateTabs()
+	m.activeTab = session.ActiveTab
+	if m.activeTab < 0 || m.activeTab >= len(m.tabs) {
+		m.activeTab = 0
+	}
+	return m.ensureTabLoaded()
+}
+
+// restoreStartupSession reopens the named or last session when enabled
+func (m *model) restoreStartupSession() tea.Cmd {
+	name := m.config.Session
+	if name == "" && !m.config.RestoreSession {
+		return nil
+	}
+	session, err := m.loadSession(name)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error loading session: %v", err)
+		}
+		return nil
+	}
+	return m.restoreSession(session)
+}
+
+// ensureTabLoaded fetches the active tab if it was restored but never loaded
+func (m *model) ensureTabLoaded() tea.Cmd {
+	tab := m.activeTabPtr()
+	if tab == nil || tab.Content != "" || tab.CurrentPos < 0 {
+		return nil
+	}
+	tab.Content = "🔄 Loading..."
+	tab.Display = tab.Content
+	if m.ready {
+		m.viewport.SetContent(tab.Display)
+	}
+	tab.updateLoading("Restoring tab...")
+	if tab.ReaderMode {
+		return fetchContentWithReaderMode(tab.URL, tab.ID)
+	}
+	return fetchContentWithLinks(tab.URL, tab.ID)
+}
+
+// scheduleSessionSave ticks the periodic autosave
+func (m *model) scheduleSessionSave() tea.Cmd {
+	if m.config.SessionSaveInterval <= 0 {
+		return nil
+	}
+	interval := time.Duration(m.config.SessionSaveInterval) * time.Second
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
+		return sessionTickMsg(t)
+	})
+}
+
+func (m *model) handleSessionTick() (tea.Model, tea.Cmd) {
+	if _, err := m.saveSession(""); err != nil {
+		log.Printf("Error saving session: %v", err)
+	}
+	return m, m.scheduleSessionSave()
+}
+
+// Handle "session save <name>", "session load <name>" and "session list"
+func (m *model) handleSessionCommand(args string) tea.Cmd {
+	activeTab := m.activeTabPtr()
+	m.urlInput.SetValue("")
+	fields := strings.Fields(args)
+
+	var cmd tea.Cmd
+	switch {
+	case len(fields) == 2 && fields[0] == "save":
+		saved, err := m.saveSession(fields[1])
+		if err != nil {
+			activeTab.setError(fmt.Sprintf("Session save failed: %v", err))
+			return nil
+		}
+		activeTab.Display = fmt.Sprintf("💾 Saved session **%s** (%d tabs)", fields[1], saved)
+		activeTab.setError("")
+
+	case len(fields) == 2 && fields[0] == "load":
+		session, err := m.loadSession(fields[1])
+		if err != nil {
+			activeTab.setError(fmt.Sprintf("Session load failed: %v", err))
+			return nil
+		}
+		cmd = m.restoreSession(session)
+		if cmd == nil {
+			activeTab.setError("Session has no tabs")
+		}
+		return cmd
+
+	default:
+		var list strings.Builder
+		list.WriteString("# Sessions\n\n")
+		names := m.listSessions()
+		if len(names) == 0 {
+			list.WriteString("No saved sessions yet.\n\n")
+		}
+		for _, name := range names {
+			list.WriteString(fmt.Sprintf("- %s\n", name))
+		}
+		list.WriteString("\nUse `session save <name>` and `session load <name>`.")
+		activeTab.Display = list.String()
+		activeTab.setError("")
+	}
+
+	if styled, err := renderWithStyle(activeTab.Display); err == nil {
+		activeTab.Display = styled
+	}
+	if m.ready {
+		m.viewport.SetContent(activeTab.Display)
+	}
+	return cmd
+}
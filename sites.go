@@ -0,0 +1,479 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Site extractors shipped with the browser, tried after the ones from
+// config.json
+var builtinExtractors = []Extractor{
+	SelectorExtractor{
+		Name:  "Wikipedia",
+		Hosts: []string{"*.wikipedia.org"},
+		Root:  "#mw-content-text .mw-parser-output",
+		Remove: []string{
+			".mw-editsection", "sup.reference", ".reflist", ".mw-references-wrap", ".navbox",
+			".hatnote", ".infobox", ".sidebar", ".metadata", ".ambox", "#toc", ".toc", "style",
+		},
+		Title: "#firstHeading",
+	},
+	SelectorExtractor{
+		Name:   "GitHub",
+		Hosts:  []string{"github.com"},
+		Root:   "article.markdown-body",
+		Remove: []string{".anchor"},
+	},
+	hackerNewsExtractor{},
+	redditExtractor{},
+	stackExchangeExtractor{},
+}
+
+// pageWriter accumulates the Markdown, numbered links and anchors of a page
+// laid out by a site extractor
+type pageWriter struct {
+	baseURL string
+	content strings.Builder
+	links   []Link
+	anchors []Anchor
+}
+
+// link numbers an http(s) link and returns its "text [n]" reference
+func (w *pageWriter) link(text, href string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if href == "" {
+		return text
+	}
+	fullURL := resolveURL(w.baseURL, href)
+	if !strings.HasPrefix(fullURL, "http") {
+		return text
+	}
+	if text == "" {
+		text = fullURL
+	}
+	number := len(w.links) + 1
+	w.links = append(w.links, Link{Number: number, Text: text, URL: href, FullURL: fullURL})
+	return fmt.Sprintf("%s [%d]", text, number)
+}
+
+// inline returns the text of s on one line with its links numbered
+func (w *pageWriter) inline(s *goquery.Selection) string {
+	cloned := s.Clone()
+	cloned.Find("code").Each(func(i int, code *goquery.Selection) {
+		code.ReplaceWithHtml(html.EscapeString("`" + code.Text() + "`"))
+	})
+	cloned.Find("a").Each(func(i int, a *goquery.Selection) {
+		a.ReplaceWithHtml(html.EscapeString(w.link(a.Text(), a.AttrOr("href", ""))))
+	})
+	return strings.Join(strings.Fields(cloned.Text()), " ")
+}
+
+// heading writes a heading the table of contents can jump to
+func (w *pageWriter) heading(level int, text string) {
+	w.anchors = append(w.anchors, Anchor{Level: level, Text: text})
+	fmt.Fprintf(&w.content, "%s %s%s\n\n", strings.Repeat("#", level), anchorMarker(len(w.anchors)-1), text)
+}
+
+// paragraph writes one block, prefixing every line with quote
+func (w *pageWriter) paragraph(quote, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		w.content.WriteString(strings.TrimRight(quote+line, " ") + "\n")
+	}
+	w.content.WriteString(strings.TrimRight(quote, " ") + "\n")
+}
+
+// end closes the current quote so the next block starts fresh
+func (w *pageWriter) end() {
+	w.content.WriteString("\n")
+}
+
+// blocks converts the paragraphs, lists, quotes, code and headings under s
+func (w *pageWriter) blocks(s *goquery.Selection, headingLevel int) {
+	s.Children().Each(func(i int, child *goquery.Selection) {
+		switch name := goquery.NodeName(child); name {
+		case "p":
+			w.paragraph("", w.inline(child))
+			w.end()
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			level, _ := strconv.Atoi(name[1:])
+			if text := strings.Join(strings.Fields(child.Text()), " "); text != "" {
+				w.heading(min(level+headingLevel-1, 6), text)
+			}
+		case "pre":
+			fmt.Fprintf(&w.content, "```\n%s\n```\n\n", strings.TrimRight(child.Text(), "\n"))
+		case "ul", "ol":
+			child.ChildrenFiltered("li").Each(func(j int, li *goquery.Selection) {
+				marker := "-"
+				if name == "ol" {
+					marker = fmt.Sprintf("%d.", j+1)
+				}
+				fmt.Fprintf(&w.content, "%s %s\n", marker, w.inline(li))
+			})
+			w.end()
+		case "blockquote":
+			w.paragraph("> ", w.inline(child))
+			w.end()
+		case "hr":
+			w.content.WriteString("---\n\n")
+		case "div", "section", "aside":
+			w.blocks(child, headingLevel)
+		case "script", "style", "noscript":
+		default:
+			w.paragraph("", w.inline(child))
+			w.end()
+		}
+	})
+}
+
+func (w *pageWriter) page(pageURL, title string) Page {
+	return newPage(pageURL, title, w.content.String(), w.links, nil, w.anchors)
+}
+
+// collapse joins the fields of s with single spaces
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// joinDetails joins the non-empty parts of a byline
+func joinDetails(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, " · ")
+}
+
+// strong puts s in bold unless it is empty
+func strong(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "**" + s + "**"
+}
+
+// hackerNewsExtractor lays out story lists and comment threads on Hacker News
+type hackerNewsExtractor struct{}
+
+func (hackerNewsExtractor) Match(pageURL string) bool {
+	return matchHost(pageURL, []string{"news.ycombinator.com"})
+}
+
+func (hackerNewsExtractor) Extract(doc *goquery.Document, pageURL string) Page {
+	w := &pageWriter{baseURL: pageURL}
+	title := ""
+
+	if item := doc.Find(".fatitem").First(); item.Length() > 0 {
+		titleLink := item.Find(".titleline > a").First()
+		title = collapse(titleLink.Text())
+		if title != "" {
+			w.heading(1, title)
+			w.paragraph("", joinDetails(
+				w.link(item.Find(".sitestr").First().Text(), titleLink.AttrOr("href", "")),
+				hackerNewsSubtext(w, item.Find(".subtext").First(), false),
+			))
+			w.end()
+		}
+		w.paragraph("", hackerNewsText(w, item.Find(".toptext").First()))
+		w.end()
+		// A comment's own page shows it at the top
+		if item.Find(".comment").Length() > 0 {
+			hackerNewsComment(w, item, 0)
+		}
+
+		comments := doc.Find("tr.athing.comtr")
+		if comments.Length() > 0 {
+			w.heading(2, fmt.Sprintf("Comments (%d)", comments.Length()))
+		}
+		comments.Each(func(i int, c *goquery.Selection) {
+			depth, _ := strconv.Atoi(c.Find("td.ind").AttrOr("indent", "0"))
+			hackerNewsComment(w, c, depth)
+		})
+	} else {
+		doc.Find("tr.athing").Not(".comtr").Each(func(i int, row *goquery.Selection) {
+			titleLink := row.Find(".titleline > a").First()
+			if titleLink.Length() == 0 {
+				return
+			}
+			line := w.link(titleLink.Text(), titleLink.AttrOr("href", ""))
+			if rank := collapse(row.Find(".rank").Text()); rank != "" {
+				line = strong(rank) + " " + line
+			}
+			if site := collapse(row.Find(".sitestr").Text()); site != "" {
+				line += " (" + site + ")"
+			}
+			details := hackerNewsSubtext(w, row.Next().Find(".subtext"), true)
+			w.paragraph("", joinDetails(line, details))
+			w.end()
+		})
+		if more := doc.Find("a.morelink").First(); more.Length() > 0 {
+			w.paragraph("", w.link(more.Text(), more.AttrOr("href", "")))
+			w.end()
+		}
+	}
+
+	if w.content.Len() == 0 {
+		return genericExtractor{}.Extract(doc, pageURL)
+	}
+	return w.page(pageURL, title)
+}
+
+// hackerNewsSubtext is the points, author, age and comment count under a story
+func hackerNewsSubtext(w *pageWriter, subtext *goquery.Selection, linkComments bool) string {
+	comments := ""
+	subtext.Find("a").Each(func(i int, a *goquery.Selection) {
+		text := collapse(a.Text())
+		if strings.HasPrefix(a.AttrOr("href", ""), "item?id=") &&
+			(strings.Contains(text, "comment") || text == "discuss") {
+			comments = text
+			if linkComments {
+				comments = w.link(text, a.AttrOr("href", ""))
+			}
+		}
+	})
+	author := collapse(subtext.Find(".hnuser").First().Text())
+	if author != "" {
+		author = "by " + author
+	}
+	return joinDetails(
+		collapse(subtext.Find(".score").First().Text()),
+		author,
+		collapse(subtext.Find(".age").First().Text()),
+		comments,
+	)
+}
+
+// hackerNewsComment writes one comment, quoted once per level of nesting
+func hackerNewsComment(w *pageWriter, c *goquery.Selection, depth int) {
+	quote := strings.Repeat("> ", depth)
+	header := joinDetails(
+		strong(collapse(c.Find(".hnuser").First().Text())),
+		collapse(c.Find(".age").First().Text()),
+	)
+	text := hackerNewsText(w, c.Find(".commtext").First())
+	if text == "" {
+		// Flagged and deleted comments have no text element
+		text = collapse(c.Find(".comment").First().Clone().Find(".reply").Remove().End().Text())
+	}
+	w.paragraph(quote, header)
+	w.paragraph(quote, text)
+	w.end()
+}
+
+// hackerNewsText splits a comment or post body into paragraphs. The first
+// paragraph is bare text; the rest are each opened by a <p>.
+func hackerNewsText(w *pageWriter, s *goquery.Selection) string {
+	paragraphs := []string{""}
+	s.Contents().Each(func(i int, node *goquery.Selection) {
+		switch goquery.NodeName(node) {
+		case "p":
+			paragraphs = append(paragraphs, w.inline(node))
+		case "a":
+			paragraphs[len(paragraphs)-1] += w.link(node.Text(), node.AttrOr("href", ""))
+		case "div":
+			// The reply link
+		default:
+			paragraphs[len(paragraphs)-1] += node.Text()
+		}
+	})
+	var kept []string
+	for _, paragraph := range paragraphs {
+		if paragraph = collapse(paragraph); paragraph != "" {
+			kept = append(kept, paragraph)
+		}
+	}
+	return strings.Join(kept, "\n\n")
+}
+
+// redditExtractor lays out listings and comment threads on Reddit, both the
+// old markup and the web components of the current site
+type redditExtractor struct{}
+
+func (redditExtractor) Match(pageURL string) bool {
+	return matchHost(pageURL, []string{"*.reddit.com"})
+}
+
+func (redditExtractor) Extract(doc *goquery.Document, pageURL string) Page {
+	w := &pageWriter{baseURL: pageURL}
+	title := ""
+	comments := pageIsRedditThread(pageURL)
+
+	// Old Reddit
+	doc.Find("#siteTable > .thing.link").Each(func(i int, thing *goquery.Selection) {
+		titleLink := thing.Find("a.title").First()
+		author := collapse(thing.Find(".tagline .author").First().Text())
+		if author != "" {
+			author = "u/" + author
+		}
+		points := collapse(thing.Find(".score.unvoted").First().AttrOr("title", ""))
+		if points != "" {
+			points += " points"
+		}
+		details := joinDetails(
+			collapse(thing.Find(".domain").First().Text()),
+			points,
+			author,
+			collapse(thing.Find(".tagline time").First().Text()),
+		)
+		if comments {
+			title = collapse(titleLink.Text())
+			w.heading(1, title)
+			w.paragraph("", joinDetails(w.link("link", titleLink.AttrOr("href", "")), details))
+			w.end()
+			w.blocks(thing.Find(".usertext-body .md").First(), 2)
+			return
+		}
+		commentsLink := thing.Find("a.comments").First()
+		w.paragraph("", joinDetails(
+			w.link(titleLink.Text(), titleLink.AttrOr("href", "")),
+			details,
+			w.link(commentsLink.Text(), commentsLink.AttrOr("href", "")),
+		))
+		w.end()
+	})
+	if thread := doc.Find(".commentarea .thing.comment"); comments && thread.Length() > 0 {
+		w.heading(2, "Comments")
+		thread.Each(func(i int, c *goquery.Selection) {
+			entry := c.ChildrenFiltered(".entry")
+			header := joinDetails(
+				strong(collapse(entry.Find(".tagline .author").First().Text())),
+				collapse(entry.Find(".tagline .score.unvoted").First().Text()),
+				collapse(entry.Find(".tagline time").First().Text()),
+			)
+			depth := c.ParentsFiltered(".thing.comment").Length()
+			w.paragraph(strings.Repeat("> ", depth), header)
+			w.paragraph(strings.Repeat("> ", depth), redditText(w, entry.Find(".usertext-body .md").First()))
+			w.end()
+		})
+	}
+
+	// Current Reddit
+	doc.Find("shreddit-post").Each(func(i int, post *goquery.Selection) {
+		details := joinDetails(
+			post.AttrOr("subreddit-prefixed-name", ""),
+			post.AttrOr("score", "0")+" points",
+			"u/"+post.AttrOr("author", ""),
+		)
+		if comments {
+			title = collapse(post.AttrOr("post-title", ""))
+			w.heading(1, title)
+			w.paragraph("", joinDetails(w.link("link", post.AttrOr("content-href", "")), details))
+			w.end()
+			w.blocks(post.Find(`[slot="text-body"]`).First(), 2)
+			return
+		}
+		w.paragraph("", joinDetails(
+			w.link(post.AttrOr("post-title", ""), post.AttrOr("permalink", "")),
+			details,
+			w.link(post.AttrOr("comment-count", "0")+" comments", post.AttrOr("permalink", "")),
+		))
+		w.end()
+	})
+	if thread := doc.Find("shreddit-comment"); comments && thread.Length() > 0 {
+		w.heading(2, "Comments")
+		thread.Each(func(i int, c *goquery.Selection) {
+			depth, _ := strconv.Atoi(c.AttrOr("depth", "0"))
+			header := joinDetails(strong(c.AttrOr("author", "")), c.AttrOr("score", "0")+" points")
+			w.paragraph(strings.Repeat("> ", depth), header)
+			w.paragraph(strings.Repeat("> ", depth), redditText(w, c.ChildrenFiltered(`[slot="comment"]`).First()))
+			w.end()
+		})
+	}
+
+	if w.content.Len() == 0 {
+		return genericExtractor{}.Extract(doc, pageURL)
+	}
+	return w.page(pageURL, title)
+}
+
+// pageIsRedditThread reports whether pageURL is a post with its comments
+func pageIsRedditThread(pageURL string) bool {
+	parsed, err := url.Parse(pageURL)
+	return err == nil && strings.Contains(parsed.Path, "/comments/")
+}
+
+// redditText joins the paragraphs of a comment body
+func redditText(w *pageWriter, s *goquery.Selection) string {
+	var paragraphs []string
+	s.Find("p, li, pre").Each(func(i int, block *goquery.Selection) {
+		if text := w.inline(block); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	})
+	return strings.Join(paragraphs, "\n\n")
+}
+
+var stackExchangeHosts = []string{
+	"stackoverflow.com", "*.stackexchange.com", "superuser.com", "serverfault.com",
+	"askubuntu.com", "mathoverflow.net", "stackapps.com",
+}
+
+var stackExchangeQuestionPath = regexp.MustCompile(`^/questions/\d+`)
+
+// stackExchangeExtractor shows a question and its answers, best first as
+// the site orders them, with code blocks kept intact
+type stackExchangeExtractor struct{}
+
+func (stackExchangeExtractor) Match(pageURL string) bool {
+	parsed, err := url.Parse(pageURL)
+	return err == nil && matchHost(pageURL, stackExchangeHosts) &&
+		stackExchangeQuestionPath.MatchString(parsed.Path)
+}
+
+func (stackExchangeExtractor) Extract(doc *goquery.Document, pageURL string) Page {
+	title := collapse(doc.Find("#question-header h1").First().Text())
+	question := doc.Find("#question").First()
+	if title == "" || question.Length() == 0 {
+		return genericExtractor{}.Extract(doc, pageURL)
+	}
+
+	w := &pageWriter{baseURL: pageURL}
+	w.heading(1, title)
+	var tags []string
+	question.Find(".post-tag").Each(func(i int, tag *goquery.Selection) {
+		tags = append(tags, collapse(tag.Text()))
+	})
+	w.paragraph("", joinDetails(stackExchangeByline(question), strings.Join(tags, ", ")))
+	w.end()
+	w.blocks(question.Find(".js-post-body").First(), 2)
+
+	answers := doc.Find("#answers .answer")
+	if answers.Length() > 0 {
+		w.heading(2, fmt.Sprintf("Answers (%d)", answers.Length()))
+	}
+	answers.Each(func(i int, answer *goquery.Selection) {
+		label := "Answer"
+		if answer.HasClass("accepted-answer") {
+			label = "✅ Accepted answer"
+		}
+		w.heading(3, joinDetails(label, stackExchangeByline(answer)))
+		w.blocks(answer.Find(".js-post-body").First(), 4)
+	})
+	return w.page(pageURL, title)
+}
+
+// stackExchangeByline is a post's votes and author
+func stackExchangeByline(post *goquery.Selection) string {
+	votes := collapse(post.Find(".js-vote-count").First().Text())
+	if votes == "1" || votes == "-1" {
+		votes += " vote"
+	} else if votes != "" {
+		votes += " votes"
+	}
+	// Edited posts list the editor first and the author last
+	author := collapse(post.Find(".post-signature .user-details [itemprop=name], .post-signature .user-details a").Last().Text())
+	if author != "" {
+		author = "by " + author
+	}
+	return joinDetails(votes, author)
+}
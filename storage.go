@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Directory name used under the XDG base directories
+const appName = "bubble-browser"
+
+// Paths says where state files live. Data holds bookmarks, history and
+// sessions, Config holds config.json and an optional help.md, and Cache
+// holds anything that can be rebuilt.
+type Paths struct {
+	Data   string
+	Config string
+	Cache  string
+}
+
+// xdgDir returns $env/bubble-browser, falling back to ~/fallback/bubble-browser
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, fallback, appName)
+}
+
+// configDir is resolved before the config is read, so it can only be
+// overridden through the environment
+func configDir() string {
+	if dir := os.Getenv("BROWSER_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// expandPath resolves a leading ~ to the home directory
+func expandPath(path string) string {
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+func resolvePaths(config Config) Paths {
+	paths := Paths{
+		Data:   xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")),
+		Config: configDir(),
+		Cache:  xdgDir("XDG_CACHE_HOME", ".cache"),
+	}
+	if config.DataDir != "" {
+		paths.Data = expandPath(config.DataDir)
+	}
+	if config.CacheDir != "" {
+		paths.Cache = expandPath(config.CacheDir)
+	}
+	for _, dir := range []string{paths.Data, paths.Cache} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("Error creating %s: %v", dir, err)
+		}
+	}
+	return paths
+}
+
+func (p Paths) data(name string) string {
+	return filepath.Join(p.Data, name)
+}
+
+// migrateLegacyFiles copies state files that older versions kept in the
+// working directory into the data directory, unless they already exist there
+func migrateLegacyFiles(paths Paths) {
+	legacy := []string{"bookmarks.json", "history.jsonl", "session.json"}
+	if named, err := filepath.Glob(filepath.Join(sessionDir, "*.json")); err == nil {
+		legacy = append(legacy, named...)
+	}
+	for _, name := range legacy {
+		target := paths.data(name)
+		if fileExists(target) || !fileExists(name) {
+			continue
+		}
+		if err := copyFile(name, target); err != nil {
+			log.Printf("Error migrating %s: %v", name, err)
+		}
+	}
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, data)
+}
+
+// writeFileAtomic writes data to a temp file in the same directory, syncs
+// it and renames it over filename so a crash never leaves a partial file
+func writeFileAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, filename)
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// modTime returns when filename last changed, or the zero time
+func modTime(filename string) time.Time {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// How long lockFile waits for another instance before going ahead without
+// the lock. It runs on the UI goroutine, so this has to stay short.
+const lockTimeout = 500 * time.Millisecond
+
+// lockFile takes an exclusive lock on filename+".lock", waiting briefly if
+// another instance holds it. Call the returned function to unlock.
+func lockFile(filename string) func() {
+	f, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		log.Printf("Error locking %s: %v", filename, err)
+		return func() {}
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		locked, err := tryFlock(f)
+		if err != nil {
+			log.Printf("Error locking %s: %v", filename, err)
+			break
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			// Writes are still atomic, so the worst case is a lost update
+			log.Printf("Error locking %s: still held by another instance", filename)
+			f.Close()
+			return func() {}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return func() {
+		funlock(f)
+		f.Close()
+	}
+}
+
+// jsonStore keeps a list in a versioned JSON file that several instances
+// may change: {"version": n, "<key>": [...]}. Changes go through update,
+// which holds the file lock and starts from what is on disk.
+type jsonStore[T any] struct {
+	file    string
+	key     string // name of the list in the file
+	version int
+	name    string    // what the list is called in log messages
+	mod     time.Time // mtime of file when last read or written
+
+	// upgrade reads files in a layout from before the versioned one, if set
+	upgrade func(data []byte) ([]T, error)
+}
+
+// load reads the list, returning an empty one if the file is missing or
+// can't be read
+func (s *jsonStore[T]) load() []T {
+	s.mod = modTime(s.file)
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return []T{}
+	}
+
+	var items []T
+	var store map[string]json.RawMessage
+	if err := json.Unmarshal(data, &store); err != nil {
+		if s.upgrade == nil {
+			log.Printf("Error loading %s: %v", s.name, err)
+			return []T{}
+		}
+		// An older layout; it's rewritten in the current one on the next save
+		if items, err = s.upgrade(data); err != nil {
+			log.Printf("Error loading %s: %v", s.name, err)
+			return []T{}
+		}
+	} else if list, ok := store[s.key]; ok {
+		if err := json.Unmarshal(list, &items); err != nil {
+			log.Printf("Error loading %s: %v", s.name, err)
+			return []T{}
+		}
+	}
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// save writes items; callers go through update so the write happens
+// under the lock
+func (s *jsonStore[T]) save(items []T) {
+	data, err := json.MarshalIndent(map[string]any{"version": s.version, s.key: items}, "", "  ")
+	if err != nil {
+		log.Printf("Error saving %s: %v", s.name, err)
+		return
+	}
+	if err := writeFileAtomic(s.file, data); err != nil {
+		log.Printf("Error writing %s file: %v", s.name, err)
+	}
+	s.mod = modTime(s.file)
+}
+
+// refresh reloads *items if another instance changed the file
+func (s *jsonStore[T]) refresh(items *[]T) {
+	if !modTime(s.file).Equal(s.mod) {
+		*items = s.load()
+	}
+}
+
+// update applies change to *items while holding the file lock, starting
+// from whatever another instance may have saved in the meantime
+func (s *jsonStore[T]) update(items *[]T, change func([]T) []T) {
+	unlock := lockFile(s.file)
+	defer unlock()
+	s.refresh(items)
+	*items = change(*items)
+	s.save(*items)
+}